@@ -1,43 +1,63 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
-	"net/http"
-	"os"
+	"time"
+	_ "time/tzdata" // база часовых поясов для контейнеров без zoneinfo
 
-	"github.com/ezfroze/go_final_project/pkg/api"
 	"github.com/ezfroze/go_final_project/pkg/db"
+	"github.com/ezfroze/go_final_project/pkg/server"
 )
 
-var dbFileDefault = "scheduler.db"
-
 func main() {
-	http.Handle("/", http.FileServer(http.Dir("./web")))
-	api.Init()
-
-	port := os.Getenv("TODO_PORT")
-	dbfile := os.Getenv("TODO_DBFILE")
+	pendingMigrations := flag.Bool("pending-migrations", false, "print migrations not yet applied to the database and exit")
+	flag.Parse()
 
-	if port == "" {
-		port = "7540"
+	// TODO_TZ - часовой пояс по умолчанию, например Europe/Moscow
+	cfg, err := server.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	if dbfile == "" {
-		dbfile = dbFileDefault
+	if *pendingMigrations {
+		printPendingMigrations(cfg.DBFile)
+		return
 	}
 
-	database, err := db.Init(dbfile)
+	database, err := db.Init(cfg.DBFile)
 
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer database.Close()
 
-	log.Println("Starting server on port " + port)
+	srv, err := server.New(cfg, db.NewSQLiteStore(database), time.Now)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Println("Starting server on port " + cfg.Port)
 
-	err = http.ListenAndServe(fmt.Sprintf(":%s", port), nil)
+	err = srv.ListenAndServe()
 	if err != nil {
 		log.Fatal(err)
 	}
 }
+
+// printPendingMigrations выводит миграции, которые будут применены к базе при запуске
+func printPendingMigrations(dbfile string) {
+	pending, err := db.PendingMigrations(dbfile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(pending) == 0 {
+		fmt.Println("no pending migrations")
+		return
+	}
+	for _, m := range pending {
+		fmt.Printf("%d %s\n", m.Version, m.Name)
+	}
+}
@@ -11,7 +11,7 @@ import (
 	"github.com/ezfroze/go_final_project/pkg/db"
 )
 
-func addTaskHandler(w http.ResponseWriter, r *http.Request) {
+func (a *API) addTaskHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Body == nil {
 		writeJSONError(w, http.StatusBadRequest, errors.New("empty request body"))
 		return
@@ -30,14 +30,24 @@ func addTaskHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	task.Date = strings.TrimSpace(task.Date)
+	task.Time = strings.TrimSpace(task.Time)
 	task.Repeat = strings.TrimSpace(task.Repeat)
+	task.RepeatUntil = strings.TrimSpace(task.RepeatUntil)
+	task.RepeatFrom = strings.TrimSpace(task.RepeatFrom)
+	task.RepeatAnchor = strings.TrimSpace(task.RepeatAnchor)
 
-	if err := checkDate(&task); err != nil {
+	now, err := a.requestNow(r)
+	if err != nil {
 		writeJSONError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	id, err := db.AddTask(&task)
+	if err := a.checkDate(&task, now); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	id, err := a.store.AddTask(&task)
 	if err != nil {
 		writeJSONError(w, http.StatusInternalServerError, err)
 		return
@@ -46,8 +56,9 @@ func addTaskHandler(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"id": strconv.FormatInt(id, 10)})
 }
 
-func checkDate(task *db.Task) error {
-	now := time.Now()
+// checkDate проверяет дату, время и повторение задачи. Сегодняшний день и
+// прошедшие даты определяются по now в часовом поясе пользователя
+func (a *API) checkDate(task *db.Task, now time.Time) error {
 	today := now.Format(Dateformat)
 
 	if task.Date == "" {
@@ -58,17 +69,59 @@ func checkDate(task *db.Task) error {
 		return err
 	}
 
+	if task.Time != "" {
+		if _, err := time.Parse(Timeformat, task.Time); err != nil {
+			return err
+		}
+	}
+
+	if task.Duration < 0 || task.Duration > maxDuration {
+		return errors.New("invalid duration")
+	}
+
+	if err := checkRepeat(task); err != nil {
+		return err
+	}
+
+	cal := a.currentCalendar()
+	if err := checkAnchor(task, now.Location(), cal); err != nil {
+		return err
+	}
+
 	var (
-		next string
-		err  error
+		next, nextTime string
+		err            error
 	)
 	if task.Repeat != "" {
-		next, err = NextDate(now, task.Date, task.Repeat)
+		var except []string
+		if task.ID != "" {
+			except, err = a.store.Exceptions(task.ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		next, nextTime, err = nextTaskDate(now, task, except, cal)
+		if errors.Is(err, ErrRepeatEnded) && task.Date >= today {
+			err = nil
+		}
 		if err != nil {
 			return err
 		}
 	}
 
+	// для правила h задача в прошлом - это задача, время которой уже прошло
+	if isHourly(task.Repeat) && next != "" {
+		start, err := time.ParseInLocation(Dateformat+Timeformat, task.Date+task.Time, now.Location())
+		if err != nil {
+			return err
+		}
+		if start.Before(now) {
+			task.Date, task.Time = next, nextTime
+		}
+		return nil
+	}
+
 	if task.Date < today {
 		if task.Repeat == "" {
 			task.Date = today
@@ -79,3 +132,158 @@ func checkDate(task *db.Task) error {
 
 	return nil
 }
+
+// isHourly сообщает, что задача повторяется по правилу h
+func isHourly(repeat string) bool {
+	parsed, err := parseRepeat(repeat)
+	return err == nil && parsed.rType == HOUR
+}
+
+// checkRepeat проверяет правило повторения, точку отсчёта и условия окончания повторений
+func checkRepeat(task *db.Task) error {
+	if err := checkRepeatRule(task); err != nil {
+		return err
+	}
+
+	if task.Repeat == "" {
+		if task.RepeatUntil != "" || task.RepeatCount != 0 {
+			return errors.New("repeat end is set without repeat")
+		}
+		if task.RepeatFrom != "" {
+			return errors.New("repeat from is set without repeat")
+		}
+		return nil
+	}
+
+	if task.RepeatFrom != RepeatFromDate && task.RepeatFrom != RepeatFromDone {
+		return errors.New("invalid repeat from")
+	}
+
+	parsed, err := parseRepeat(task.Repeat)
+	if err != nil {
+		return err
+	}
+
+	if parsed.rType == HOUR && task.Time == "" {
+		return errors.New("time is required for hourly repeat")
+	}
+
+	// правило RRULE сохраняем в формате d/w/m/y, если это возможно
+	if isRRule(task.Repeat) {
+		if repeat, ok := formatRepeat(parsed); ok {
+			task.Repeat = repeat
+		}
+	}
+
+	if task.RepeatCount < 0 {
+		return errors.New("invalid repeat count")
+	}
+	if task.RepeatCount == 0 {
+		task.RepeatCount = parsed.count
+	}
+
+	if task.RepeatUntil != "" {
+		if _, err := time.Parse(Dateformat, task.RepeatUntil); err != nil {
+			return err
+		}
+		if task.RepeatUntil < task.Date {
+			return errors.New("repeat until is before task date")
+		}
+	}
+
+	return nil
+}
+
+// checkAnchor переносит дату задачи с правилом fwd или back на рабочий день и запоминает
+// исходную дату в RepeatAnchor. Якорь сохраняется, если дата задачи - одно из повторений серии от него.
+// При repeat_from == done серия начинается заново при каждом выполнении, поэтому якорь не нужен
+func checkAnchor(task *db.Task, loc *time.Location, cal Calendar) error {
+	if task.Repeat == "" {
+		task.RepeatAnchor = ""
+		return nil
+	}
+
+	parsed, err := parseRepeat(task.Repeat)
+	if err != nil {
+		return err
+	}
+	if parsed.roll == 0 {
+		task.RepeatAnchor = ""
+		return nil
+	}
+
+	date, err := time.ParseInLocation(Dateformat, task.Date, loc)
+	if err != nil {
+		return err
+	}
+
+	if task.RepeatAnchor != "" && task.RepeatFrom == RepeatFromDate {
+		anchor, err := time.ParseInLocation(Dateformat, task.RepeatAnchor, loc)
+		if err != nil {
+			return err
+		}
+		if rollDate(anchor, parsed.roll, cal).Equal(date) {
+			return nil
+		}
+		next, _, err := nextDateTime(date.Add(-time.Second), task.RepeatAnchor, "", task.Repeat, cal)
+		if err == nil && next == task.Date {
+			return nil
+		}
+	}
+
+	task.RepeatAnchor = task.Date
+	if task.RepeatFrom == RepeatFromDone {
+		task.RepeatAnchor = ""
+	}
+	task.Date = rollDate(date, parsed.roll, cal).Format(Dateformat)
+	return nil
+}
+
+// nextDoneDate возвращает дату и время задачи после её выполнения в момент now.
+// При repeat_from == done правило отсчитывается от момента выполнения, а не от даты задачи
+func nextDoneDate(now time.Time, task *db.Task, except []string, cal Calendar) (string, string, error) {
+	if task.RepeatFrom != RepeatFromDone {
+		return nextTaskDate(now, task, except, cal)
+	}
+
+	fromDone := *task
+	fromDone.Date = now.Format(Dateformat)
+	fromDone.RepeatAnchor = ""
+	if isHourly(task.Repeat) {
+		fromDone.Time = now.Format(Timeformat)
+	}
+	return nextTaskDate(now, &fromDone, except, cal)
+}
+
+// nextTaskDate возвращает следующие дату и время задачи с учётом дат-исключений
+// и даты окончания повторений
+func nextTaskDate(now time.Time, task *db.Task, except []string, cal Calendar) (string, string, error) {
+	dstart := task.Date
+	if task.RepeatAnchor != "" {
+		// серия с переносом считается от якоря, но следующее повторение должно быть позже текущего
+		dstart = task.RepeatAnchor
+		day, err := time.ParseInLocation(Dateformat, task.Date, now.Location())
+		if err != nil {
+			return "", "", err
+		}
+		now = laterOf(now, day.AddDate(0, 0, 1).Add(-time.Second))
+	}
+
+	parsed, err := parseRepeat(task.Repeat)
+	if err != nil {
+		return "", "", err
+	}
+	// COUNT у задачи учитывается в repeat_count, который уменьшается при каждом выполнении
+	parsed.count = 0
+
+	next, nextTime, err := nextParsedExcept(now, dstart, task.Time, parsed, except, cal)
+	if err != nil {
+		return "", "", err
+	}
+
+	if task.RepeatUntil != "" && next > task.RepeatUntil {
+		return "", "", ErrRepeatEnded
+	}
+
+	return next, nextTime, nil
+}
@@ -3,29 +3,100 @@ package api
 import (
 	"errors"
 	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ezfroze/go_final_project/pkg/db"
+)
+
+const (
+	Dateformat = "20060102"
+	Timeformat = "15:04"
+)
+
+// maxDuration - наибольшая длительность задачи в минутах
+const maxDuration = 24 * 60
+
+// Точка отсчёта повторений задачи
+const (
+	RepeatFromDate = ""     // от даты задачи
+	RepeatFromDone = "done" // от дня выполнения
 )
 
-const Dateformat = "20060102"
+// API - обработчики планировщика. У каждого экземпляра свои хранилище, часы, часовой пояс,
+// пароль и производственный календарь, поэтому в одном процессе может работать несколько экземпляров
+type API struct {
+	store    db.Store
+	now      func() time.Time
+	location *time.Location
+	password string
+	// trashDays - сколько дней удалённые задачи хранятся в корзине
+	trashDays int
+
+	calendarMu sync.RWMutex
+	calendar   Calendar
+}
+
+// Options - зависимости API. Пустые Now и Location заменяются на time.Now и time.Local,
+// пустой Password отключает авторизацию, нулевой TrashDays - автоматическую очистку корзины
+type Options struct {
+	Store     db.Store
+	Now       func() time.Time
+	Location  *time.Location
+	Password  string
+	TrashDays int
+}
+
+// New возвращает API с пустым производственным календарём
+func New(opts Options) *API {
+	a := &API{
+		store:     opts.Store,
+		now:       opts.Now,
+		location:  opts.Location,
+		password:  opts.Password,
+		trashDays: opts.TrashDays,
+		calendar:  HolidayList(nil),
+	}
+	if a.now == nil {
+		a.now = time.Now
+	}
+	if a.location == nil {
+		a.location = time.Local
+	}
+	return a
+}
 
-func taskHandler(w http.ResponseWriter, r *http.Request) {
+func (a *API) taskHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodPost:
-		addTaskHandler(w, r)
+		a.addTaskHandler(w, r)
 	case http.MethodGet:
-		getTask(w, r)
+		a.getTask(w, r)
 	case http.MethodPut:
-		updateTask(w, r)
+		a.updateTask(w, r)
 	case http.MethodDelete:
-		deleteTaskHandler(w, r)
+		a.deleteTaskHandler(w, r)
 	default:
 		writeJSONError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
 	}
 }
 
-func Init() {
-	http.HandleFunc("/api/nextdate", nextDateHandler)
-	http.HandleFunc("/api/task", auth(taskHandler))
-	http.HandleFunc("/api/tasks", auth(tasksHandler))
-	http.HandleFunc("/api/task/done", auth(doneTaskHandler))
-	http.HandleFunc("/api/signin", signInHandler)
+// Register регистрирует обработчики API в mux
+func (a *API) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/api/nextdate", a.nextDateHandler)
+	mux.HandleFunc("/api/repeat/convert", repeatConvertHandler)
+	mux.HandleFunc("/api/repeat/describe", repeatDescribeHandler)
+	mux.HandleFunc("/api/task", a.auth(a.taskHandler))
+	mux.HandleFunc("/api/tasks", a.auth(a.tasksHandler))
+	mux.HandleFunc("/api/occurrences", a.auth(a.occurrencesHandler))
+	mux.HandleFunc("/api/task/quick", a.auth(a.quickTaskHandler))
+	mux.HandleFunc("/api/task/done", a.auth(a.doneTaskHandler))
+	mux.HandleFunc("/api/task/skip", a.auth(a.skipTaskHandler))
+	mux.HandleFunc("/api/completions", a.auth(a.completionsHandler))
+	mux.HandleFunc("/api/task/exceptions", a.auth(a.exceptionsHandler))
+	mux.HandleFunc("/api/trash", a.auth(a.trashHandler))
+	mux.HandleFunc("/api/trash/restore", a.auth(a.restoreTaskHandler))
+	mux.HandleFunc("/api/holidays", a.auth(a.holidaysHandler))
+	mux.HandleFunc("/api/holidays/import", a.auth(a.holidaysImportHandler))
+	mux.HandleFunc("/api/signin", a.signInHandler)
 }
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"sort"
+	"time"
+)
+
+// Calendar - производственный календарь для правила b и переноса дат fwd/back.
+// Суббота и воскресенье всегда нерабочие, календарь добавляет к ним праздники
+type Calendar interface {
+	// Holidays возвращает праздники YYYYMMDD в диапазоне from..to включительно
+	Holidays(from, to string) []string
+}
+
+// HolidayList - календарь из списка праздничных дат YYYYMMDD
+type HolidayList []string
+
+// NewHolidayList возвращает календарь из дат в любом порядке
+func NewHolidayList(dates []string) HolidayList {
+	list := make(HolidayList, len(dates))
+	copy(list, dates)
+	sort.Strings(list)
+	return list
+}
+
+func (l HolidayList) Holidays(from, to string) []string {
+	start := sort.SearchStrings(l, from)
+	end := sort.SearchStrings(l, to)
+	if end < len(l) && l[end] == to {
+		end++
+	}
+	if start >= end {
+		return nil
+	}
+	return l[start:end]
+}
+
+// calendarOrWeekends возвращает cal или, если он не задан, календарь без праздников
+func calendarOrWeekends(cal Calendar) Calendar {
+	if cal == nil {
+		return HolidayList(nil)
+	}
+	return cal
+}
+
+// SetCalendar заменяет производственный календарь экземпляра API
+func (a *API) SetCalendar(c Calendar) {
+	a.calendarMu.Lock()
+	defer a.calendarMu.Unlock()
+	a.calendar = c
+}
+
+func (a *API) currentCalendar() Calendar {
+	a.calendarMu.RLock()
+	defer a.calendarMu.RUnlock()
+	return a.calendar
+}
+
+// LoadHolidays загружает праздники из хранилища в производственный календарь экземпляра API
+func (a *API) LoadHolidays() error {
+	holidays, err := a.store.Holidays()
+	if err != nil {
+		return err
+	}
+
+	dates := make([]string, 0, len(holidays))
+	for _, h := range holidays {
+		dates = append(dates, h.Date)
+	}
+	a.SetCalendar(NewHolidayList(dates))
+	return nil
+}
+
+func isWeekend(date time.Time) bool {
+	return date.Weekday() == time.Saturday || date.Weekday() == time.Sunday
+}
+
+func isWorkday(cal Calendar, date time.Time) bool {
+	if isWeekend(date) {
+		return false
+	}
+	day := date.Format(Dateformat)
+	return len(cal.Holidays(day, day)) == 0
+}
+
+// workdaysBetween возвращает количество рабочих дней в диапазоне from..to включительно
+func workdaysBetween(cal Calendar, from, to time.Time) int {
+	if to.Before(from) {
+		return 0
+	}
+
+	total := daysBetween(from, to) + 1
+	count := total / 7 * 5
+	for i, date := 0, from.AddDate(0, 0, total/7*7); i < total%7; i, date = i+1, date.AddDate(0, 0, 1) {
+		if !isWeekend(date) {
+			count++
+		}
+	}
+
+	// праздники в выходные уже не учтены
+	for _, day := range cal.Holidays(from.Format(Dateformat), to.Format(Dateformat)) {
+		date, err := time.ParseInLocation(Dateformat, day, from.Location())
+		if err == nil && !isWeekend(date) {
+			count--
+		}
+	}
+	return count
+}
+
+// nextBusiness - первая дата, до которой от start прошло k*workdays рабочих дней (k >= 1),
+// не раньше minDate. Дни до minDate считаются по календарю, а не перебором
+func nextBusiness(start, minDate time.Time, workdays int, cal Calendar) time.Time {
+	date := start.AddDate(0, 0, 1)
+	passed := 0
+	if minDate.After(date) {
+		passed = workdaysBetween(cal, date, minDate.AddDate(0, 0, -1))
+		date = minDate
+	}
+
+	need := workdays - passed%workdays
+	for {
+		if isWorkday(cal, date) {
+			need--
+			if need == 0 {
+				return date
+			}
+		}
+		date = date.AddDate(0, 0, 1)
+	}
+}
+
+// rollDate переносит нерабочий день на ближайший рабочий: вперёд при direction = 1, назад при -1
+func rollDate(date time.Time, direction int, cal Calendar) time.Time {
+	for !isWorkday(cal, date) {
+		date = date.AddDate(0, 0, direction)
+	}
+	return date
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ezfroze/go_final_project/pkg/db"
+)
+
+type CompletionsResp struct {
+	Completions []db.Completion `json:"completions"`
+	// Streak - серии выполнений, только для истории повторяющейся задачи
+	Streak *Streak `json:"streak,omitempty"`
+}
+
+// Streak - серии повторений, выполненных вовремя подряд. Серия прерывается, если повторение
+// выполнено позже своего дня, пропущено, перенесено или не выполнено
+type Streak struct {
+	// Current - текущая серия. 0, если следующее повторение уже просрочено или повторений больше нет
+	Current int `json:"current"`
+	// Best - самая длинная серия за всю историю
+	Best int `json:"best"`
+}
+
+// completionsHandler возвращает историю выполнений, последние первыми: задачи id, дни выполнения
+// от from до to включительно или то и другое вместе. Для повторяющейся задачи считаются серии
+func (a *API) completionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	id := r.FormValue("id")
+	from := r.FormValue("from")
+	to := r.FormValue("to")
+	if from != "" {
+		if _, err := time.Parse(Dateformat, from); err != nil {
+			writeJSONError(w, http.StatusBadRequest, errors.New("incorrect from"))
+			return
+		}
+	}
+	if to != "" {
+		if _, err := time.Parse(Dateformat, to); err != nil {
+			writeJSONError(w, http.StatusBadRequest, errors.New("incorrect to"))
+			return
+		}
+	}
+	if from != "" && to != "" && from > to {
+		writeJSONError(w, http.StatusBadRequest, errors.New("from is after to"))
+		return
+	}
+
+	completions, err := a.store.Completions(id, from, to)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	resp := CompletionsResp{Completions: completions}
+
+	if id != "" {
+		now, err := a.requestNow(r)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		// серии считаются по всей истории задачи, а не только по диапазону
+		history, err := a.store.Completions(id, "", "")
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		// задачи в корзине и удалённой задачи нет, но история и лучшая серия остаются
+		task, err := a.store.GetTask(id)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		if (task != nil && task.Repeat != "") || hasNextDate(history) {
+			streak := completionStreak(history, task, now.Format(Dateformat))
+			resp.Streak = &streak
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// hasNextDate сообщает, что задача хотя бы раз переносилась на следующее повторение
+func hasNextDate(history []db.Completion) bool {
+	for _, c := range history {
+		if c.NextDate != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// completionStreak считает серии по истории задачи, последние выполнения первыми.
+// Выполнение продолжает серию, если сделано не позже своего дня и выполняет то повторение,
+// на которое задачу перенесло предыдущее выполнение. task - задача сейчас, nil - задача удалена
+func completionStreak(history []db.Completion, task *db.Task, today string) Streak {
+	var (
+		streak   Streak
+		run      int
+		prevNext string
+	)
+	for i := len(history) - 1; i >= 0; i-- {
+		c := history[i]
+		switch {
+		case c.DoneDate > c.Date:
+			run = 0
+		case prevNext != "" && c.Date == prevNext:
+			run++
+		default:
+			run = 1
+		}
+		streak.Best = max(streak.Best, run)
+		prevNext = c.NextDate
+	}
+
+	// серия жива, пока задача ждёт следующего повторения и оно не просрочено
+	if task != nil && prevNext != "" && task.Date == prevNext && task.Date >= today {
+		streak.Current = run
+	}
+	return streak
+}
@@ -3,11 +3,10 @@ package api
 import (
 	"errors"
 	"net/http"
-
-	"github.com/ezfroze/go_final_project/pkg/db"
 )
 
-func deleteTaskHandler(w http.ResponseWriter, r *http.Request) {
+// deleteTaskHandler переносит задачу в корзину
+func (a *API) deleteTaskHandler(w http.ResponseWriter, r *http.Request) {
 	id := r.FormValue("id")
 
 	if id == "" {
@@ -15,7 +14,7 @@ func deleteTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := db.DeleteTask(id)
+	err := a.store.DeleteTask(id, a.now())
 	if err != nil {
 		writeJSONError(w, http.StatusInternalServerError, err)
 		return
@@ -0,0 +1,480 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Языки описания правил повторения
+const (
+	LangRU = "ru"
+	LangEN = "en"
+)
+
+// DescribeRepeat возвращает описание правила повторения на языке lang (ru или en)
+// DescribeRepeat("m 1,-1 2,8", "ru") = 1-го числа и в последний день февраля и августа
+// DescribeRepeat("m 1,-1 2,8", "en") = on the 1st and last day of February and August
+func DescribeRepeat(repeat, lang string) (string, error) {
+	parsed, err := parseRepeat(repeat)
+	if err != nil {
+		return "", err
+	}
+
+	switch lang {
+	case LangRU:
+		return describeRU(parsed), nil
+	case LangEN:
+		return describeEN(parsed), nil
+	}
+	return "", errors.New("unsupported language")
+}
+
+// requestLang возвращает язык из параметра lang или заголовка Accept-Language, по умолчанию русский
+func requestLang(r *http.Request) string {
+	lang := r.FormValue("lang")
+	if lang == "" {
+		lang = r.Header.Get("Accept-Language")
+	}
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(lang)), LangEN) {
+		return LangEN
+	}
+	return LangRU
+}
+
+type RepeatDescribeResp struct {
+	Description string `json:"description"`
+}
+
+// repeatDescribeHandler возвращает описание правила repeat на языке lang
+func repeatDescribeHandler(w http.ResponseWriter, r *http.Request) {
+	repeat := strings.TrimSpace(r.FormValue("repeat"))
+	lang := r.FormValue("lang")
+	if lang == "" {
+		lang = requestLang(r)
+	}
+
+	description, err := DescribeRepeat(repeat, lang)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, RepeatDescribeResp{Description: description})
+}
+
+var (
+	ruWeekdaysDative = [7]string{"воскресеньям", "понедельникам", "вторникам", "средам", "четвергам", "пятницам", "субботам"}
+	ruWeekdaysAccus  = [7]string{"воскресенье", "понедельник", "вторник", "среду", "четверг", "пятницу", "субботу"}
+	// род дня недели для согласования порядкового числительного: m, f, n
+	ruWeekdaysGender = [7]byte{'n', 'm', 'm', 'f', 'm', 'f', 'f'}
+	ruMonthsGenitive = [13]string{"", "января", "февраля", "марта", "апреля", "мая", "июня",
+		"июля", "августа", "сентября", "октября", "ноября", "декабря"}
+	ruMonthsPrepos = [13]string{"", "январе", "феврале", "марте", "апреле", "мае", "июне",
+		"июле", "августе", "сентябре", "октябре", "ноябре", "декабре"}
+	// порядковые числительные по родам: первый - пятый и с конца
+	ruOrdinals = map[byte][5]string{
+		'm': {"первый", "второй", "третий", "четвёртый", "пятый"},
+		'f': {"первую", "вторую", "третью", "четвёртую", "пятую"},
+		'n': {"первое", "второе", "третье", "четвёртое", "пятое"},
+	}
+	ruLast = map[byte][2]string{
+		'm': {"последний", "предпоследний"},
+		'f': {"последнюю", "предпоследнюю"},
+		'n': {"последнее", "предпоследнее"},
+	}
+)
+
+// ruPlural выбирает форму слова для числа n: 1 день, 2 дня, 5 дней
+func ruPlural(n int, one, few, many string) string {
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return one
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return few
+	}
+	return many
+}
+
+// ruEvery - "каждый день", "каждые 3 дня", "каждый 21 день". every - "каждый" или "каждую"
+func ruEvery(n int, every, one, few, many string) string {
+	if n == 1 {
+		return every + " " + one
+	}
+	word := ruPlural(n, one, few, many)
+	if word == one {
+		return fmt.Sprintf("%s %d %s", every, n, word)
+	}
+	return fmt.Sprintf("каждые %d %s", n, word)
+}
+
+// ruJoin соединяет части через запятую и "и" перед последней
+func ruJoin(parts []string) string {
+	return joinWords(parts, " и ")
+}
+
+func enJoin(parts []string) string {
+	return joinWords(parts, " and ")
+}
+
+func joinWords(parts []string, and string) string {
+	if len(parts) <= 1 {
+		return strings.Join(parts, "")
+	}
+	return strings.Join(parts[:len(parts)-1], ", ") + and + parts[len(parts)-1]
+}
+
+// ruPreposition возвращает "во" перед словами на "вт", иначе "в"
+func ruPreposition(word string) string {
+	if strings.HasPrefix(word, "вт") {
+		return "во"
+	}
+	return "в"
+}
+
+func ruNthWeekday(w nthWeekday) string {
+	gender := ruWeekdaysGender[w.weekday]
+	var ordinal string
+	switch {
+	case w.n > 0:
+		ordinal = ruOrdinals[gender][w.n-1]
+	case w.n >= -2:
+		ordinal = ruLast[gender][-w.n-1]
+	default:
+		ordinal = ruOrdinals[gender][-w.n-1] + " с конца"
+	}
+	return ruPreposition(ordinal) + " " + ordinal + " " + ruWeekdaysAccus[w.weekday]
+}
+
+// ruMonthDays - "1-го и 15-го числа", "в последний день", "по понедельникам"
+func ruMonthDays(parsed *Parsed) []string {
+	numbers := make([]string, 0, len(parsed.days))
+	parts := make([]string, 0, len(parsed.days)+len(parsed.weekdays)+1)
+	for _, day := range parsed.days {
+		switch {
+		case day > 0:
+			numbers = append(numbers, fmt.Sprintf("%d-го", day))
+		case day == -1:
+			parts = append(parts, "в последний день")
+		case day == -2:
+			parts = append(parts, "в предпоследний день")
+		default:
+			parts = append(parts, fmt.Sprintf("в %d-й день с конца", -day))
+		}
+	}
+	if len(numbers) > 0 {
+		parts = append([]string{ruJoin(numbers) + " числа"}, parts...)
+	}
+
+	every := make([]string, 0)
+	for _, w := range parsed.weekdays {
+		if w.n == 0 {
+			every = append(every, ruWeekdaysDative[w.weekday])
+			continue
+		}
+		parts = append(parts, ruNthWeekday(w))
+	}
+	if len(every) > 0 {
+		parts = append(parts, "по "+ruJoin(every))
+	}
+	return parts
+}
+
+func describeRU(parsed *Parsed) string {
+	var text string
+
+	switch parsed.rType {
+	case DAY:
+		text = ruEvery(parsed.days[0], "каждый", "день", "дня", "дней")
+	case YEAR:
+		text = ruEvery(parsed.interval, "каждый", "год", "года", "лет")
+		if len(parsed.yearDays) > 0 {
+			days := make([]string, 0, len(parsed.yearDays))
+			for _, d := range parsed.yearDays {
+				days = append(days, fmt.Sprintf("%d %s", d.day, ruMonthsGenitive[d.month]))
+			}
+			text += " " + ruJoin(days)
+		}
+		switch leapDay(parsed) {
+		case 1:
+			text += ", в невисокосные годы 29 февраля переносится на 1 марта"
+		case -1:
+			text += ", в невисокосные годы 29 февраля переносится на 28 февраля"
+		}
+	case HOUR:
+		text = ruEvery(parsed.interval, "каждый", "час", "часа", "часов")
+	case BUSINESSDAY:
+		text = ruEvery(parsed.interval, "каждый", "рабочий день", "рабочих дня", "рабочих дней")
+	case WEEKDAY:
+		text = ruEvery(parsed.interval, "каждую", "неделю", "недели", "недель")
+		if len(parsed.days) > 0 {
+			days := make([]string, 0, len(parsed.days))
+			for _, day := range parsed.days {
+				days = append(days, ruWeekdaysDative[day%7])
+			}
+			if parsed.interval == 1 {
+				text = "по " + ruJoin(days)
+			} else {
+				text += " по " + ruJoin(days)
+			}
+		}
+	case MONTH:
+		parts := ruMonthDays(parsed)
+
+		months := make([]string, 0, len(parsed.months))
+		for _, month := range parsed.months {
+			if len(parts) == 0 {
+				months = append(months, ruMonthsPrepos[month])
+			} else {
+				months = append(months, ruMonthsGenitive[month])
+			}
+		}
+
+		switch {
+		case len(months) == 0:
+			text = ruEvery(parsed.interval, "каждый", "месяц", "месяца", "месяцев")
+			if len(parts) > 0 {
+				text += " " + ruJoin(parts)
+			}
+		case len(parts) == 0:
+			text = "в " + ruJoin(months)
+		default:
+			text = ruJoin(parts) + " " + ruJoin(months)
+		}
+
+		if len(parsed.setPos) > 0 {
+			positions := make([]string, 0, len(parsed.setPos))
+			for _, pos := range parsed.setPos {
+				switch {
+				case pos == -1:
+					positions = append(positions, "последний")
+				case pos == -2:
+					positions = append(positions, "предпоследний")
+				case pos < 0:
+					positions = append(positions, fmt.Sprintf("%d-й с конца", -pos))
+				default:
+					positions = append(positions, fmt.Sprintf("%d-й", pos))
+				}
+			}
+			text += ", только " + ruJoin(positions) + " из этих дней"
+		}
+
+		switch last, first := clampedDays(parsed); {
+		case last && first:
+			text += ", в коротких месяцах - в последний или первый день"
+		case last:
+			text += ", в коротких месяцах - в последний день"
+		case first:
+			text += ", в коротких месяцах - в первый день"
+		}
+	}
+
+	switch parsed.roll {
+	case 1:
+		text += ", выходные переносятся на следующий рабочий день"
+	case -1:
+		text += ", выходные переносятся на предыдущий рабочий день"
+	}
+
+	if parsed.count > 0 {
+		text += fmt.Sprintf(", всего %d %s", parsed.count, ruPlural(parsed.count, "раз", "раза", "раз"))
+	}
+	if parsed.until != "" {
+		if until, err := time.Parse(Dateformat, parsed.until); err == nil {
+			text += ", до " + until.Format("02.01.2006")
+		}
+	}
+
+	return text
+}
+
+var (
+	enOrdinals = [5]string{"first", "second", "third", "fourth", "fifth"}
+	enLast     = [5]string{"last", "second to last", "third to last", "fourth to last", "fifth to last"}
+)
+
+// enOrdinal - 1st, 2nd, 3rd, 4th, 11th, 21st
+func enOrdinal(n int) string {
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+// enEvery - "every day", "every 3 days"
+func enEvery(n int, one, many string) string {
+	if n == 1 {
+		return "every " + one
+	}
+	return fmt.Sprintf("every %d %s", n, many)
+}
+
+func describeEN(parsed *Parsed) string {
+	var text string
+
+	switch parsed.rType {
+	case DAY:
+		text = enEvery(parsed.days[0], "day", "days")
+	case YEAR:
+		text = enEvery(parsed.interval, "year", "years")
+		if len(parsed.yearDays) > 0 {
+			days := make([]string, 0, len(parsed.yearDays))
+			for _, d := range parsed.yearDays {
+				days = append(days, fmt.Sprintf("%s %d", time.Month(d.month), d.day))
+			}
+			text += " on " + enJoin(days)
+		}
+		switch leapDay(parsed) {
+		case 1:
+			text += ", February 29 moves to March 1 in common years"
+		case -1:
+			text += ", February 29 moves to February 28 in common years"
+		}
+	case HOUR:
+		text = enEvery(parsed.interval, "hour", "hours")
+	case BUSINESSDAY:
+		text = enEvery(parsed.interval, "business day", "business days")
+	case WEEKDAY:
+		text = enEvery(parsed.interval, "week", "weeks")
+		if len(parsed.days) > 0 {
+			days := make([]string, 0, len(parsed.days))
+			for _, day := range parsed.days {
+				days = append(days, time.Weekday(day%7).String())
+			}
+			text += " on " + enJoin(days)
+		}
+	case MONTH:
+		// порядковые дни пишутся с одним артиклем: the 1st and last day
+		parts := make([]string, 0, len(parsed.days)+len(parsed.weekdays))
+		every := make([]string, 0)
+		for _, day := range parsed.days {
+			switch {
+			case day > 0:
+				parts = append(parts, enOrdinal(day))
+			case -day <= len(enLast):
+				parts = append(parts, enLast[-day-1]+" day")
+			default:
+				parts = append(parts, enOrdinal(-day)+" to last day")
+			}
+		}
+		for _, w := range parsed.weekdays {
+			switch {
+			case w.n == 0:
+				every = append(every, w.weekday.String())
+			case w.n > 0:
+				parts = append(parts, enOrdinals[w.n-1]+" "+w.weekday.String())
+			default:
+				parts = append(parts, enLast[-w.n-1]+" "+w.weekday.String())
+			}
+		}
+
+		days := make([]string, 0, 2)
+		if len(parts) > 0 {
+			days = append(days, "the "+enJoin(parts))
+		}
+		if len(every) > 0 {
+			days = append(days, "every "+enJoin(every))
+		}
+
+		months := make([]string, 0, len(parsed.months))
+		for _, month := range parsed.months {
+			months = append(months, time.Month(month).String())
+		}
+
+		switch {
+		case len(months) == 0:
+			text = enEvery(parsed.interval, "month", "months")
+			if len(days) > 0 {
+				text += " on " + enJoin(days)
+			}
+		case len(days) == 0:
+			text = "every year in " + enJoin(months)
+		default:
+			text = "on " + enJoin(days) + " of " + enJoin(months)
+		}
+
+		if len(parsed.setPos) > 0 {
+			positions := make([]string, 0, len(parsed.setPos))
+			for _, pos := range parsed.setPos {
+				if pos > 0 {
+					positions = append(positions, enOrdinal(pos))
+				} else if -pos <= len(enLast) {
+					positions = append(positions, enLast[-pos-1])
+				} else {
+					positions = append(positions, enOrdinal(-pos)+" to last")
+				}
+			}
+			text += ", only the " + enJoin(positions) + " of these days"
+		}
+
+		switch last, first := clampedDays(parsed); {
+		case last && first:
+			text += ", on the last or first day in shorter months"
+		case last:
+			text += ", on the last day in shorter months"
+		case first:
+			text += ", on the first day in shorter months"
+		}
+	}
+
+	switch parsed.roll {
+	case 1:
+		text += ", moved to the next business day if it falls on a day off"
+	case -1:
+		text += ", moved to the previous business day if it falls on a day off"
+	}
+
+	if parsed.count == 1 {
+		text += ", once"
+	} else if parsed.count > 1 {
+		text += fmt.Sprintf(", %d times", parsed.count)
+	}
+	if parsed.until != "" {
+		if until, err := time.Parse(Dateformat, parsed.until); err == nil {
+			text += ", until " + until.Format("January 2, 2006")
+		}
+	}
+
+	return text
+}
+
+// clampedDays сообщает, есть ли у правила с clamp дни, которых нет в коротких месяцах:
+// last - после 28-го числа, first - раньше 28-го дня с конца
+func clampedDays(parsed *Parsed) (last, first bool) {
+	if !parsed.clamp {
+		return false, false
+	}
+	for _, day := range parsed.days {
+		last = last || day > 28
+		first = first || day < -28
+	}
+	return last, first
+}
+
+// leapDay сообщает, куда правило y переносит 29 февраля в невисокосные годы:
+// 1 - на 1 марта, -1 - на 28 февраля (clamp), 0 - правило явно не включает 29 февраля
+func leapDay(parsed *Parsed) int {
+	if parsed.clamp && len(parsed.yearDays) == 0 {
+		return -1
+	}
+	for _, d := range parsed.yearDays {
+		if d.month == 2 && d.day == 29 {
+			if parsed.clamp {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -1,45 +1,90 @@
 package api
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/ezfroze/go_final_project/pkg/db"
 )
 
-func doneTaskHandler(w http.ResponseWriter, r *http.Request) {
+// DoneReq - необязательное тело запроса /api/task/done
+type DoneReq struct {
+	// Note - заметка к выполнению, сохраняется в истории
+	Note string `json:"note"`
+}
+
+// doneTaskHandler отмечает задачу выполненной: повторяющаяся задача переносится на следующую дату,
+// остальные - в корзину. Выполнение записывается в историю
+func (a *API) doneTaskHandler(w http.ResponseWriter, r *http.Request) {
 	id := r.FormValue("id")
 
 	if id == "" {
 		writeJSONError(w, http.StatusBadRequest, errors.New("id is required"))
-	}
-
-	task, err := db.GetTask(id)
-	if err != nil {
-		writeJSONError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	if task.Repeat == "" {
-		err := db.DeleteTask(id)
+	var req DoneReq
+	if r.Body != nil {
+		data, err := io.ReadAll(r.Body)
 		if err != nil {
-			writeJSONError(w, http.StatusInternalServerError, err)
+			writeJSONError(w, http.StatusBadRequest, err)
 			return
 		}
+		if len(bytes.TrimSpace(data)) > 0 {
+			if err = json.Unmarshal(data, &req); err != nil {
+				writeJSONError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+	}
 
-		writeJSON(w, http.StatusOK, map[string]interface{}{})
+	task, err := a.store.GetTask(id)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	nextDate, err := NextDate(time.Now(), task.Date, task.Repeat)
+	completion := db.Completion{
+		TaskID: id,
+		Title:  task.Title,
+		Date:   task.Date,
+		Time:   task.Time,
+		Note:   strings.TrimSpace(req.Note),
+	}
+
+	now, err := a.requestNow(r)
 	if err != nil {
-		writeJSONError(w, http.StatusInternalServerError, err)
+		writeJSONError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	err = db.UpdateTaskDate(id, nextDate)
-	if err != nil {
+	var nextDate, nextTime string
+	// при repeat_count == 1 выполняется последнее повторение
+	if task.Repeat != "" && task.RepeatCount != 1 {
+		except, err := a.store.Exceptions(id)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		nextDate, nextTime, err = nextDoneDate(now, task, except, a.currentCalendar())
+		if err != nil && !errors.Is(err, ErrRepeatEnded) {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	// задача без повторения или с закончившимися повторениями переносится в корзину,
+	// запись о выполнении и перенос задачи сохраняются вместе
+	completion.NextDate = nextDate
+	completion.DoneDate = now.Format(Dateformat)
+	completion.CompletedAt = now.UTC().Format(time.RFC3339)
+	if _, err := a.store.CompleteTask(&completion, nextTime, a.now()); err != nil {
 		writeJSONError(w, http.StatusInternalServerError, err)
 		return
 	}
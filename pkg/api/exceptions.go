@@ -0,0 +1,57 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+type ExceptionsResp struct {
+	Exceptions []string `json:"exceptions"`
+}
+
+// exceptionsHandler работает с датами-исключениями задачи:
+// GET - список, POST - добавить дату, DELETE - удалить дату
+func (a *API) exceptionsHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.FormValue("id")
+
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, errors.New("id is required"))
+		return
+	}
+
+	if _, err := a.store.GetTask(id); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	date := r.FormValue("date")
+
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if _, err = time.Parse(Dateformat, date); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		err = a.store.AddException(id, date)
+	case http.MethodDelete:
+		err = a.store.DeleteException(id, date)
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	except, err := a.store.Exceptions(id)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ExceptionsResp{Exceptions: except})
+}
@@ -3,11 +3,9 @@ package api
 import (
 	"errors"
 	"net/http"
-
-	"github.com/ezfroze/go_final_project/pkg/db"
 )
 
-func getTask(w http.ResponseWriter, r *http.Request) {
+func (a *API) getTask(w http.ResponseWriter, r *http.Request) {
 	id := r.FormValue("id")
 
 	if id == "" {
@@ -15,11 +13,12 @@ func getTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	task, err := db.GetTask(id)
+	task, err := a.store.GetTask(id)
 	if err != nil {
 		writeJSONError(w, http.StatusInternalServerError, err)
 		return
 	}
 
+	fillRepeat(task, requestLang(r))
 	writeJSON(w, http.StatusOK, task)
 }
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ezfroze/go_final_project/pkg/db"
+)
+
+type HolidaysResp struct {
+	Holidays []db.Holiday `json:"holidays"`
+}
+
+// holidaysHandler работает с праздниками производственного календаря:
+// GET - список, POST - добавить праздник {"date", "title"}, DELETE - удалить по date
+func (a *API) holidaysHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		holidays, err := a.store.Holidays()
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, HolidaysResp{Holidays: holidays})
+		return
+	case http.MethodPost:
+		if r.Body == nil {
+			writeJSONError(w, http.StatusBadRequest, errors.New("empty request body"))
+			return
+		}
+
+		var holiday db.Holiday
+		if err := json.NewDecoder(r.Body).Decode(&holiday); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		holiday.Date = strings.TrimSpace(holiday.Date)
+		holiday.Title = strings.TrimSpace(holiday.Title)
+		if _, err := time.Parse(Dateformat, holiday.Date); err != nil {
+			writeJSONError(w, http.StatusBadRequest, errors.New("incorrect date"))
+			return
+		}
+
+		if err := a.store.AddHolidays([]db.Holiday{holiday}); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+	case http.MethodDelete:
+		if err := a.store.DeleteHoliday(r.FormValue("date")); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	if err := a.LoadHolidays(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// holidaysImportHandler добавляет праздники из файла .ics, переданного в теле запроса
+func (a *API) holidaysImportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	if r.Body == nil {
+		writeJSONError(w, http.StatusBadRequest, errors.New("empty request body"))
+		return
+	}
+
+	holidays, err := parseICS(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err = a.store.AddHolidays(holidays); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err = a.LoadHolidays(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int{"imported": len(holidays)})
+}
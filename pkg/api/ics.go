@@ -0,0 +1,185 @@
+package api
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ezfroze/go_final_project/pkg/db"
+)
+
+// icsRepeatYears - на сколько лет вперёд раскрываются повторяющиеся события календаря
+const icsRepeatYears = 10
+
+// parseICS читает праздники из календаря iCalendar (RFC 5545). Каждый день события
+// становится праздником, события с RRULE раскрываются на icsRepeatYears лет
+func parseICS(r io.Reader) ([]db.Holiday, error) {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, err
+	}
+
+	holidays := make([]db.Holiday, 0)
+	var (
+		inEvent          bool
+		start, end       string
+		title, rrule     string
+		hasStart, hasEnd bool
+	)
+
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		// параметры свойства, например DTSTART;VALUE=DATE, не важны
+		name, _, _ = strings.Cut(strings.ToUpper(name), ";")
+
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			inEvent = true
+			start, end, title, rrule = "", "", "", ""
+			hasStart, hasEnd = false, false
+		case name == "END" && value == "VEVENT":
+			if !inEvent {
+				return nil, errors.New("ics: unexpected END:VEVENT")
+			}
+			inEvent = false
+			if !hasStart {
+				return nil, errors.New("ics: event without DTSTART")
+			}
+
+			days, err := icsEventDays(start, end, hasEnd)
+			if err != nil {
+				return nil, err
+			}
+			dates, err := icsRepeatDates(start, rrule)
+			if err != nil {
+				return nil, err
+			}
+			for _, date := range dates {
+				for i := 0; i < days; i++ {
+					day, err := time.Parse(Dateformat, date)
+					if err != nil {
+						return nil, err
+					}
+					holidays = append(holidays, db.Holiday{
+						Date:  day.AddDate(0, 0, i).Format(Dateformat),
+						Title: title,
+					})
+				}
+			}
+		case !inEvent:
+		case name == "DTSTART":
+			start, hasStart = icsDate(value), true
+		case name == "DTEND":
+			end, hasEnd = icsDate(value), true
+		case name == "SUMMARY":
+			title = unescapeICS(value)
+		case name == "RRULE":
+			rrule = value
+		}
+	}
+
+	if inEvent {
+		return nil, errors.New("ics: event is not closed")
+	}
+
+	return holidays, nil
+}
+
+// unfoldICS склеивает перенесённые строки: продолжение строки начинается с пробела или табуляции
+func unfoldICS(r io.Reader) ([]string, error) {
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// icsDate возвращает дату YYYYMMDD из DATE (20250101) или DATE-TIME (20250101T090000Z)
+func icsDate(value string) string {
+	if len(value) > len(Dateformat) {
+		return value[:len(Dateformat)]
+	}
+	return value
+}
+
+// icsEventDays возвращает длительность события в днях. DTEND в календаре не входит в событие
+func icsEventDays(start, end string, hasEnd bool) (int, error) {
+	from, err := time.Parse(Dateformat, start)
+	if err != nil {
+		return 0, errors.New("ics: invalid DTSTART")
+	}
+	if !hasEnd || end <= start {
+		return 1, nil
+	}
+
+	to, err := time.Parse(Dateformat, end)
+	if err != nil {
+		return 0, errors.New("ics: invalid DTEND")
+	}
+	return daysBetween(from, to), nil
+}
+
+// icsRepeatDates возвращает даты начала события с учётом RRULE
+func icsRepeatDates(start, rrule string) ([]string, error) {
+	dates := []string{start}
+	if rrule == "" {
+		return dates, nil
+	}
+
+	parsed, err := parseRRule(rrule)
+	if err != nil {
+		return nil, err
+	}
+
+	// праздник - это весь день, повторения чаще раза в день не имеют смысла
+	if parsed.rType == HOUR {
+		return nil, errors.New("ics: RRULE FREQ=HOURLY is not supported")
+	}
+
+	first, err := time.Parse(Dateformat, start)
+	if err != nil {
+		return nil, err
+	}
+	last := first.AddDate(icsRepeatYears, 0, 0).Format(Dateformat)
+
+	// серия обходится один раз от даты к дате, COUNT считается здесь, а не в каждом шаге
+	count := parsed.count
+	parsed.count = 0
+	cursor := first
+	for count == 0 || len(dates) < count {
+		date, _, err := nextOccurrence(cursor, start, "", parsed, HolidayList(nil))
+		if errors.Is(err, ErrRepeatEnded) || errors.Is(err, ErrDateOutOfRange) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if date > last {
+			break
+		}
+		dates = append(dates, date)
+
+		// следующее повторение ищется после всего этого дня
+		cursor, err = time.Parse(Dateformat, date)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return dates, nil
+}
+
+func unescapeICS(value string) string {
+	return strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\N`, " ", `\\`, `\`).Replace(value)
+}
@@ -15,117 +15,337 @@ const (
 	DAY     = "d"
 	MONTH   = "m"
 	YEAR    = "y"
+	HOUR    = "h"
+	// BUSINESSDAY - каждые N рабочих дней
+	BUSINESSDAY = "b"
 )
 
-var repeatTypes = []string{WEEKDAY, DAY, MONTH, YEAR}
+var repeatTypes = []string{WEEKDAY, DAY, MONTH, YEAR, HOUR, BUSINESSDAY}
+
+// Модификаторы правил d, w, m и y, переносящие нерабочий день на рабочий
+const (
+	rollForward  = "fwd"  // на следующий рабочий день
+	rollBackward = "back" // на предыдущий рабочий день
+)
+
+// clampDays - модификатор правил m и y: дни, которых нет в коротком месяце (31, -31),
+// переносятся на последний или первый день месяца, а не пропускаются.
+// Для y это 29 февраля: без clamp в невисокосный год оно переходит на 1 марта, с clamp - на 28 февраля
+const clampDays = "clamp"
 
 // NextDate return format - YYYYMMDD
 // NextDate(now, "20240229", "y") = 20250301
 // NextDate(now, "20240113", "d 7") = 20240120
 // NextDate(now, "20240116", "m 16,5") = 20240205
 // NextDate(now, "20240201", "m -1,18") = 20240218
+// NextDate(now, "20240101", "w 1,4 2") = 20240129
+// NextDate(now, "20240101", "m 2tu") = 20240213
+// NextDate(now, "20240105", "b 5") = 20240112
+// NextDate(now, "20240101", "m 6 fwd") = 20240108
+// Рабочие дни для правил b, fwd и back считаются без праздников, только по выходным
 func NextDate(now time.Time, dstart string, repeat string) (string, error) {
-	parsedDate, err := time.Parse(Dateformat, dstart)
+	next, _, err := NextDateTime(now, dstart, "", repeat, nil)
+	return next, err
+}
+
+// NextDateTime работает как NextDate, но учитывает время задачи tstart (HH:MM) и
+// производственный календарь cal. cal == nil - календарь без праздников.
+// Правило h возвращает новое время, остальные правила время не меняют
+// NextDateTime(now, "20240126", "09:30", "h 4", nil) = 20240126 13:30
+func NextDateTime(now time.Time, dstart, tstart, repeat string, cal Calendar) (string, string, error) {
+	return nextDateTime(now, dstart, tstart, repeat, calendarOrWeekends(cal))
+}
+
+// nextDateTime работает как NextDateTime по производственному календарю cal
+func nextDateTime(now time.Time, dstart, tstart, repeat string, cal Calendar) (string, string, error) {
+	parsedRepeat, err := parseRepeat(repeat)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
-	parsedRepeat, err := parseRepeat(repeat)
+	return nextCounted(now, dstart, tstart, parsedRepeat, cal)
+}
+
+// nextCounted возвращает следующее повторение после now. Серия с COUNT считается от dstart,
+// сама дата задачи - первое повторение, как в NextDates
+func nextCounted(now time.Time, dstart, tstart string, parsedRepeat *Parsed, cal Calendar) (string, string, error) {
+	if parsedRepeat.count == 0 {
+		return nextOccurrence(now, dstart, tstart, parsedRepeat, cal)
+	}
+
+	clock := tstart
+	if clock == "" {
+		clock = "00:00"
+	}
+	cursor, err := time.ParseInLocation(Dateformat+Timeformat, dstart+clock, now.Location())
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
-	if parsedRepeat.rType == DAY {
-		for {
-			parsedDate = parsedDate.AddDate(0, 0, parsedRepeat.days[0])
-			if afterNow(parsedDate, now) {
-				break
-			}
+	today := now.Format(Dateformat)
+	for left := parsedRepeat.count - 1; left > 0; left-- {
+		next, nextTime, err := nextOccurrence(cursor, dstart, tstart, parsedRepeat, cal)
+		if err != nil {
+			return "", "", err
 		}
+
+		if nextTime != "" {
+			clock = nextTime
+		}
+		cursor, err = time.ParseInLocation(Dateformat+Timeformat, next+clock, now.Location())
+		if err != nil {
+			return "", "", err
+		}
+
+		if (parsedRepeat.rType == HOUR && cursor.After(now)) || (parsedRepeat.rType != HOUR && next > today) {
+			return next, nextTime, nil
+		}
+	}
+
+	return "", "", ErrRepeatEnded
+}
+
+// nextOccurrence возвращает следующее повторение после now без учёта COUNT
+func nextOccurrence(now time.Time, dstart, tstart string, parsedRepeat *Parsed, cal Calendar) (string, string, error) {
+	// дата и время задачи - это дата и время в часовом поясе now
+	parsedDate, err := time.ParseInLocation(Dateformat, dstart, now.Location())
+	if err != nil {
+		return "", "", err
 	}
 
-	if parsedRepeat.rType == YEAR {
+	var next time.Time
+	if parsedRepeat.rType == HOUR {
+		clock := tstart
+		if clock == "" {
+			clock = "00:00"
+		}
+		start, err := time.ParseInLocation(Dateformat+Timeformat, dstart+clock, now.Location())
+		if err != nil {
+			return "", "", err
+		}
+
+		next = nextHourly(start, now, parsedRepeat.interval)
+		tstart = next.Format(Timeformat)
+	} else {
+		if tstart != "" {
+			if _, err := time.Parse(Timeformat, tstart); err != nil {
+				return "", "", err
+			}
+		}
+
+		// ближайший день, который наступит после now
+		minDate := firstDayAfter(now, parsedDate.Location())
+
 		for {
-			parsedDate = parsedDate.AddDate(1, 0, 0)
-			if afterNow(parsedDate, now) {
+			switch parsedRepeat.rType {
+			case DAY:
+				next = nextDaily(parsedDate, minDate, parsedRepeat.days[0])
+			case YEAR:
+				next = nextYearly(parsedDate, minDate, parsedRepeat)
+			case WEEKDAY:
+				next = nextWeekly(parsedDate, minDate, parsedRepeat)
+			case MONTH:
+				next, err = nextMonthly(parsedDate, minDate, parsedRepeat)
+				if err != nil {
+					return "", "", err
+				}
+			case BUSINESSDAY:
+				next = nextBusiness(parsedDate, minDate, parsedRepeat.interval, cal)
+			}
+
+			if parsedRepeat.roll == 0 {
+				break
+			}
+
+			// при переносе назад дата может оказаться раньше minDate, тогда берём следующее повторение
+			rolled := rollDate(next, parsedRepeat.roll, cal)
+			if !rolled.Before(minDate) {
+				next = rolled
 				break
 			}
+			minDate = next.AddDate(0, 0, 1)
 		}
 	}
 
-	if parsedRepeat.rType == WEEKDAY {
-		var dayOfWeek [7]bool
-		for _, day := range parsedRepeat.days {
-			idx := day % 7 // 7 -> 0 (воскресенье), 1..6 -> 1..6
-			dayOfWeek[idx] = true
+	if next.Year() > 9999 {
+		return "", "", ErrDateOutOfRange
+	}
+
+	nextDate := next.Format(Dateformat)
+	if parsedRepeat.until != "" && nextDate > parsedRepeat.until {
+		return "", "", ErrRepeatEnded
+	}
+
+	return nextDate, tstart, nil
+}
+
+// NextDateExcept работает как NextDateTime, но пропускает даты из except (YYYYMMDD)
+func NextDateExcept(now time.Time, dstart, tstart, repeat string, except []string, cal Calendar) (string, string, error) {
+	return nextDateExcept(now, dstart, tstart, repeat, except, calendarOrWeekends(cal))
+}
+
+func nextDateExcept(now time.Time, dstart, tstart, repeat string, except []string, cal Calendar) (string, string, error) {
+	parsed, err := parseRepeat(repeat)
+	if err != nil {
+		return "", "", err
+	}
+
+	return nextParsedExcept(now, dstart, tstart, parsed, except, cal)
+}
+
+func nextParsedExcept(now time.Time, dstart, tstart string, parsed *Parsed, except []string, cal Calendar) (string, string, error) {
+	for {
+		next, clock, err := nextCounted(now, dstart, tstart, parsed, cal)
+		if err != nil || !slices.Contains(except, next) {
+			return next, clock, err
 		}
 
-		for {
-			if afterNow(parsedDate, now) {
-				weekdayIdx := int(parsedDate.Weekday())
-				if dayOfWeek[weekdayIdx] {
-					break
-				}
-			}
-			parsedDate = parsedDate.AddDate(0, 0, 1)
+		// пропускаем весь день: для правила h в нём может быть несколько повторений
+		day, err := time.ParseInLocation(Dateformat, next, now.Location())
+		if err != nil {
+			return "", "", err
 		}
+		now = day.AddDate(0, 0, 1).Add(-time.Second)
+	}
+}
+
+// NextDates возвращает до count ближайших повторений после now в формате ответа /api/nextdate:
+// YYYYMMDD или YYYYMMDD HH:MM, если задано время tstart. Для правил с COUNT серия считается
+// от dstart, сама дата задачи - первое повторение. cal - как в NextDateTime
+// NextDates(now, "20240101", "", "w 1,4", 3, nil) = [20240129 20240201 20240205] при now = 20240126
+func NextDates(now time.Time, dstart, tstart, repeat string, count int, cal Calendar) ([]string, error) {
+	return nextDates(now, dstart, tstart, repeat, count, calendarOrWeekends(cal))
+}
+
+func nextDates(now time.Time, dstart, tstart, repeat string, count int, cal Calendar) ([]string, error) {
+	parsed, err := parseRepeat(repeat)
+	if err != nil {
+		return nil, err
 	}
 
-	if parsedRepeat.rType == MONTH {
-		var dayOfMonth [32]bool
-		lastDay := false
-		secondLastDay := false
-		var months [13]bool
-		allMonths := len(parsedRepeat.months) == 0
+	clock := tstart
+	if clock == "" {
+		clock = "00:00"
+	}
 
-		for _, day := range parsedRepeat.days {
-			switch {
-			case day > 0:
-				dayOfMonth[day] = true
-			case day == -1:
-				lastDay = true
-			case day == -2:
-				secondLastDay = true
-			}
+	cursor := now
+	left := 0 // сколько повторений серии с COUNT ещё осталось, 0 - без ограничения
+	if parsed.count > 0 {
+		if parsed.count == 1 {
+			return nil, ErrRepeatEnded
+		}
+		left = parsed.count - 1
+		cursor, err = time.ParseInLocation(Dateformat+Timeformat, dstart+clock, now.Location())
+		if err != nil {
+			return nil, err
 		}
+	}
 
-		if allMonths {
-			for i := 1; i <= 12; i++ {
-				months[i] = true
-			}
-		} else {
-			for _, month := range parsedRepeat.months {
-				months[month] = true
+	today := now.Format(Dateformat)
+	dates := make([]string, 0, count)
+	for len(dates) < count {
+		next, nextTime, err := nextOccurrence(cursor, dstart, tstart, parsed, cal)
+		if errors.Is(err, ErrRepeatEnded) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if nextTime != "" {
+			clock = nextTime
+		}
+		cursor, err = time.ParseInLocation(Dateformat+Timeformat, next+clock, now.Location())
+		if err != nil {
+			return nil, err
+		}
+
+		// при обходе серии с COUNT от dstart пропускаем уже прошедшие повторения
+		if (parsed.rType == HOUR && cursor.After(now)) || (parsed.rType != HOUR && next > today) {
+			if tstart != "" {
+				next += " " + nextTime
 			}
+			dates = append(dates, next)
 		}
 
-		for {
-			if afterNow(parsedDate, now) {
-				monthIdx := parsedDate.Month()
-				if months[monthIdx] && validMonthlyDate(parsedDate, &dayOfMonth, lastDay, secondLastDay) {
-					break
-				}
+		if left > 0 {
+			left--
+			if left == 0 {
+				break
 			}
-			parsedDate = parsedDate.AddDate(0, 0, 1)
 		}
 	}
 
-	return parsedDate.Format(Dateformat), nil
+	if len(dates) == 0 {
+		return nil, ErrRepeatEnded
+	}
+	return dates, nil
 }
 
+// ErrRepeatEnded возвращается, когда у правила больше нет повторений
+var ErrRepeatEnded = errors.New("repeat has ended")
+
+// ErrNoOccurrences возвращается для правил, которые никогда не выполняются, например m 30 2
+var ErrNoOccurrences = errors.New("repeat has no occurrences")
+
+// ErrDateOutOfRange возвращается, когда следующая дата не помещается в формат YYYYMMDD
+var ErrDateOutOfRange = errors.New("next date is out of range")
+
+// За 400 лет (4800 месяцев) григорианский календарь вместе с днями недели повторяется,
+// поэтому если правило не выполнилось за это время, оно не выполнится никогда
+const monthsInCycle = 4800
+
 type Parsed struct {
-	rType  string
-	days   []int
-	months []int
+	rType    string
+	days     []int
+	weekdays []nthWeekday
+	months   []int
+	interval int
+	setPos   []int
+	count    int
+	until    string
+	// roll - перенос нерабочего дня: 1 - вперёд, -1 - назад, 0 - без переноса
+	roll int
+	// clamp - несуществующие дни месяца переносятся на последний или первый день
+	clamp bool
+	// yearDays - дни года правила y: y 25.12,01.01
+	yearDays []yearDay
+}
+
+// yearDay - день и месяц в правиле y
+type yearDay struct {
+	day   int
+	month int
+}
+
+// nthWeekday - n-й день недели в месяце: 2tu - второй вторник, -1fr - последняя пятница.
+// n == 0 - каждый такой день недели (BYDAY=MO в RRULE)
+type nthWeekday struct {
+	n       int
+	weekday time.Weekday
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"mo": time.Monday,
+	"tu": time.Tuesday,
+	"we": time.Wednesday,
+	"th": time.Thursday,
+	"fr": time.Friday,
+	"sa": time.Saturday,
+	"su": time.Sunday,
 }
 
 func parseRepeat(repeat string) (*Parsed, error) {
-	result := new(Parsed)
+	result := &Parsed{interval: 1}
 
 	if repeat == "" {
 		return result, errors.New("repeat is empty")
 	}
 
+	if isRRule(repeat) {
+		return parseRRule(repeat)
+	}
+
 	params := strings.Fields(repeat)
 	if len(params) == 0 {
 		return result, errors.New("repeat is empty")
@@ -137,6 +357,31 @@ func parseRepeat(repeat string) (*Parsed, error) {
 		return result, errors.New("invalid repeat type")
 	}
 
+	// fwd и back - последний параметр правил d, w, m и y
+	if len(rParams) > 0 {
+		switch rParams[len(rParams)-1] {
+		case rollForward:
+			result.roll = 1
+		case rollBackward:
+			result.roll = -1
+		}
+		if result.roll != 0 {
+			if rType == HOUR || rType == BUSINESSDAY {
+				return result, errors.New("roll is not supported for this repeat type")
+			}
+			rParams = rParams[:len(rParams)-1]
+		}
+	}
+
+	// clamp - последний параметр правил m и y перед fwd или back
+	if len(rParams) > 0 && rParams[len(rParams)-1] == clampDays {
+		if rType != MONTH && rType != YEAR {
+			return result, errors.New("clamp is supported only for month and year repeat")
+		}
+		result.clamp = true
+		rParams = rParams[:len(rParams)-1]
+	}
+
 	// d - day - max 400
 	// example - d 1, d 7, d 60
 	if rType == DAY {
@@ -155,19 +400,92 @@ func parseRepeat(repeat string) (*Parsed, error) {
 		result.days = []int{days}
 	}
 
+	// y - year
+	// y [DD.MM,DD.MM] [N] - N - интервал в годах от года даты задачи, max 100
+	// example - y; y 2; y 25.12,01.01; y 15.03 5; y 29.02 clamp
 	if rType == YEAR {
-		if len(rParams) > 0 {
+		if len(rParams) > 0 && strings.Contains(rParams[0], ".") {
+			yearDays, err := parseYearDays(rParams[0])
+			if err != nil {
+				return result, err
+			}
+			result.yearDays = yearDays
+			rParams = rParams[1:]
+		}
+
+		if len(rParams) > 1 {
 			return result, errors.New("invalid year repeat params")
 		}
+
+		if len(rParams) == 1 {
+			interval, err := strconv.Atoi(rParams[0])
+			if err != nil {
+				return result, err
+			}
+
+			if interval < 1 || interval > 100 {
+				return result, errors.New("invalid year repeat interval")
+			}
+			result.interval = interval
+		}
 	}
 
-	// w - weak
-	// example - w 7; w 1,4,5; w 2,3;
-	if rType == WEEKDAY {
+	// h - hour - max 168
+	// example - h 1, h 4, h 36
+	if rType == HOUR {
+		if len(rParams) != 1 {
+			return result, errors.New("invalid hour repeat params")
+		}
+
+		hours, err := strconv.Atoi(rParams[0])
+		if err != nil {
+			return result, err
+		}
+
+		if hours <= 0 || hours > 168 {
+			return result, errors.New("invalid hour repeat params")
+		}
+		result.interval = hours
+	}
+
+	// b - business day - max 250
+	// example - b 1, b 5
+	if rType == BUSINESSDAY {
 		if len(rParams) != 1 {
+			return result, errors.New("invalid business day repeat params")
+		}
+
+		workdays, err := strconv.Atoi(rParams[0])
+		if err != nil {
+			return result, err
+		}
+
+		if workdays <= 0 || workdays > 250 {
+			return result, errors.New("invalid business day repeat params")
+		}
+		result.interval = workdays
+	}
+
+	// w - week
+	// w D,D [N] - N - интервал в неделях, max 52
+	// example - w 7; w 1,4,5; w 2,3; w 1,4 2
+	if rType == WEEKDAY {
+		if len(rParams) == 0 || len(rParams) > 2 {
 			return result, errors.New("invalid weekday repeat params")
 		}
 
+		if len(rParams) == 2 {
+			interval, err := strconv.Atoi(rParams[1])
+			if err != nil {
+				return result, err
+			}
+
+			if interval < 1 || interval > 52 {
+				return result, errors.New("invalid weekday repeat interval")
+			}
+			result.interval = interval
+		}
+
 		daysArr := strings.Split(rParams[0], ",")
 
 		if len(daysArr) == 0 || len(daysArr) > 7 {
@@ -198,7 +516,8 @@ func parseRepeat(repeat string) (*Parsed, error) {
 
 	// m - month date
 	// m D,D M,M
-	// example - m 4; m 1,15,25; m -1; m -2; m 3 1,3,6; m 1,-1 2,8
+	// D - день месяца 1..31, день от конца месяца -1..-31 или n-й день недели: 1..5 или -1..-5 и mo,tu,we,th,fr,sa,su
+	// example - m 4; m 1,15,25; m -1; m -3; m 3 1,3,6; m 1,-1 2,8; m 2tu; m -1fr 3,9; m 31 clamp
 	if rType == MONTH {
 		if len(rParams) == 0 || len(rParams) > 2 {
 			return result, errors.New("invalid month repeat params")
@@ -215,21 +534,35 @@ func parseRepeat(repeat string) (*Parsed, error) {
 		}
 
 		parsedDaysArr := make([]int, 0, len(daysArr))
+		parsedWeekdaysArr := make([]nthWeekday, 0)
 		for _, day := range daysArr {
 			day = strings.TrimSpace(day)
 			if day == "" {
 				return result, errors.New("invalid month repeat params")
 			}
+
+			if len(day) > 2 {
+				if weekday, ok := weekdayNames[day[len(day)-2:]]; ok {
+					n, err := strconv.Atoi(day[:len(day)-2])
+					if err != nil {
+						return result, err
+					}
+
+					if n == 0 || n < -5 || n > 5 {
+						return result, errors.New("invalid month repeat params")
+					}
+
+					parsedWeekdaysArr = append(parsedWeekdaysArr, nthWeekday{n: n, weekday: weekday})
+					continue
+				}
+			}
+
 			count, err := strconv.Atoi(day)
 			if err != nil {
 				return result, err
 			}
 
-			if count == 0 || count > 31 {
-				return result, errors.New("invalid month repeat params")
-			}
-
-			if count < 0 && count != -1 && count != -2 {
+			if count == 0 || count > 31 || count < -31 {
 				return result, errors.New("invalid month repeat params")
 			}
 
@@ -258,6 +591,7 @@ func parseRepeat(repeat string) (*Parsed, error) {
 
 		result.months = parsedMonthsArr
 		result.days = parsedDaysArr
+		result.weekdays = parsedWeekdaysArr
 	}
 
 	result.rType = rType
@@ -265,55 +599,379 @@ func parseRepeat(repeat string) (*Parsed, error) {
 	return result, nil
 }
 
-func afterNow(date, now time.Time) bool {
-	return date.After(now)
+// parseYearDays разбирает дни года правила y: 25.12,01.01
+func parseYearDays(value string) ([]yearDay, error) {
+	items := strings.Split(value, ",")
+	yearDays := make([]yearDay, 0, len(items))
+	for _, item := range items {
+		day, month, ok := strings.Cut(strings.TrimSpace(item), ".")
+		if !ok {
+			return nil, errors.New("invalid year repeat params")
+		}
+
+		d, err := strconv.Atoi(day)
+		if err != nil {
+			return nil, err
+		}
+		m, err := strconv.Atoi(month)
+		if err != nil {
+			return nil, err
+		}
+
+		if m < 1 || m > 12 || d < 1 || d > daysInMonthMax[m] {
+			return nil, errors.New("invalid year repeat params")
+		}
+		yearDays = append(yearDays, yearDay{day: d, month: m})
+	}
+	return yearDays, nil
 }
 
-func isLastDayOfMonth(date time.Time) bool {
-	return date.AddDate(0, 0, 1).Month() != date.Month()
+// firstDayAfter возвращает первую полночь в loc, которая позже now
+func firstDayAfter(now time.Time, loc *time.Location) time.Time {
+	local := now.In(loc)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	if !day.After(now) {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
 }
 
-func isSecondLastDayOfMonth(date time.Time) bool {
-	return date.AddDate(0, 0, 2).Month() != date.Month()
+// daysBetween возвращает количество календарных дней от from до to
+func daysBetween(from, to time.Time) int {
+	fromDay := time.Date(from.Year(), from.Month(), from.Day(), 12, 0, 0, 0, time.UTC)
+	toDay := time.Date(to.Year(), to.Month(), to.Day(), 12, 0, 0, 0, time.UTC)
+	// Sub ограничен ~292 годами, поэтому считаем через Unix
+	return int((toDay.Unix() - fromDay.Unix()) / (24 * 60 * 60))
 }
 
-func validMonthlyDate(date time.Time, dayOfMonth *[32]bool, lastDay, secondLastDay bool) bool {
-	day := date.Day()
-	if dayOfMonth[day] {
-		return true
+func laterOf(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
 	}
-	if lastDay && isLastDayOfMonth(date) {
-		return true
+	return b
+}
+
+// nextDaily - первая дата start + k*days (k >= 1), не раньше minDate
+func nextDaily(start, minDate time.Time, days int) time.Time {
+	lower := laterOf(minDate, start.AddDate(0, 0, days))
+	steps := (daysBetween(start, lower) + days - 1) / days
+	return start.AddDate(0, 0, steps*days)
+}
+
+// nextHourly - первое время start + k*hours (k >= 1), позже now
+func nextHourly(start, now time.Time, hours int) time.Time {
+	step := int64(hours) * 60 * 60
+	steps := int64(1)
+	if elapsed := now.Unix() - start.Unix(); elapsed >= step {
+		steps = elapsed/step + 1
+	}
+	return time.Unix(start.Unix()+steps*step, 0).In(start.Location())
+}
+
+// nextYearly - годовщина даты начала раз в interval лет, не раньше minDate. 29 февраля в невисокосный
+// год переходит на 1 марта, а с clamp - на 28 февраля. Для правила с днями года - первый из них
+// в годах серии, не раньше start и minDate
+func nextYearly(start, minDate time.Time, parsed *Parsed) time.Time {
+	if len(parsed.yearDays) > 0 {
+		return nextYearDays(start, minDate, parsed)
+	}
+
+	// годы серии: start.Year() + k*interval, k >= 1
+	k := max(minDate.Year()-start.Year(), 1)
+	if rest := k % parsed.interval; rest != 0 {
+		k += parsed.interval - rest
+	}
+
+	next := yearDate(start.Year()+k, start.Month(), start.Day(), parsed.clamp, start.Location())
+	if next.Before(minDate) {
+		next = yearDate(start.Year()+k+parsed.interval, start.Month(), start.Day(), parsed.clamp, start.Location())
+	}
+	return next
+}
+
+func nextYearDays(start, minDate time.Time, parsed *Parsed) time.Time {
+	lower := laterOf(minDate, start)
+
+	k := lower.Year() - start.Year()
+	if rest := k % parsed.interval; rest != 0 {
+		k += parsed.interval - rest
+	}
+
+	// каждый день года есть в любом году, поэтому хватает двух лет серии
+	for ; ; k += parsed.interval {
+		var next time.Time
+		for _, d := range parsed.yearDays {
+			date := yearDate(start.Year()+k, time.Month(d.month), d.day, parsed.clamp, start.Location())
+			if !date.Before(lower) && (next.IsZero() || date.Before(next)) {
+				next = date
+			}
+		}
+		if !next.IsZero() {
+			return next
+		}
+	}
+}
+
+// yearDate возвращает день day месяца month года year. 29 февраля в невисокосный год -
+// это 1 марта, а с clamp - 28 февраля
+func yearDate(year int, month time.Month, day int, clamp bool, loc *time.Location) time.Time {
+	date := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	if clamp && date.Month() != month {
+		date = time.Date(year, month+1, 0, 0, 0, 0, 0, loc)
+	}
+	return date
+}
+
+// nextWeekly - первая подходящая дата в активной неделе, не раньше start и minDate
+func nextWeekly(start, minDate time.Time, parsed *Parsed) time.Time {
+	var dayOfWeek [7]bool
+	for _, day := range parsed.days {
+		idx := day % 7 // 7 -> 0 (воскресенье), 1..6 -> 1..6
+		dayOfWeek[idx] = true
+	}
+	// правило RRULE без BYDAY повторяется в день недели даты начала
+	if len(parsed.days) == 0 {
+		dayOfWeek[start.Weekday()] = true
+	}
+
+	lower := laterOf(minDate, start)
+
+	// отсчёт недель ведётся от недели, в которую попадает дата начала
+	anchor := weekStart(start)
+	week := weeksBetween(anchor, lower)
+	if rest := week % parsed.interval; rest != 0 {
+		week += parsed.interval - rest
+	}
+
+	// в первой активной неделе подходящие дни могут уже пройти, тогда дата будет в следующей
+	for {
+		monday := anchor.AddDate(0, 0, week*7)
+		for i := 0; i < 7; i++ {
+			date := monday.AddDate(0, 0, i)
+			if !date.Before(lower) && dayOfWeek[date.Weekday()] {
+				return date
+			}
+		}
+		week += parsed.interval
+	}
+}
+
+// nextMonthly перебирает только подходящие месяцы начиная с месяца, в который попадает
+// max(start, minDate). Перебор ограничен циклом календаря в monthsInCycle месяцев
+func nextMonthly(start, minDate time.Time, parsed *Parsed) (time.Time, error) {
+	// правило RRULE без BYMONTHDAY и BYDAY повторяется в день месяца даты начала.
+	// Разобранное правило не меняем: его могут использовать с другой датой начала
+	if len(parsed.days) == 0 && len(parsed.weekdays) == 0 {
+		local := *parsed
+		local.days = []int{start.Day()}
+		parsed = &local
+	}
+
+	var months [13]bool
+	if len(parsed.months) == 0 {
+		for i := 1; i <= 12; i++ {
+			months[i] = true
+		}
+	} else {
+		for _, month := range parsed.months {
+			months[month] = true
+		}
+	}
+
+	if !parsed.clamp && !monthlyPossible(parsed, &months) {
+		return time.Time{}, ErrNoOccurrences
+	}
+
+	lower := laterOf(minDate, start)
+
+	offset := monthsBetween(start, lower)
+	if rest := offset % parsed.interval; rest != 0 {
+		offset += parsed.interval - rest
 	}
-	if secondLastDay && isSecondLastDayOfMonth(date) {
+	first := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, start.Location())
+
+	for i := 0; i < monthsInCycle; i, offset = i+1, offset+parsed.interval {
+		month := first.AddDate(0, offset, 0)
+		if month.Year() > 9999 {
+			return time.Time{}, ErrDateOutOfRange
+		}
+		if parsed.until != "" && month.Format(Dateformat) > parsed.until {
+			return time.Time{}, ErrRepeatEnded
+		}
+		if !months[month.Month()] {
+			continue
+		}
+
+		for _, date := range monthlyDates(month, parsed) {
+			if !date.Before(lower) {
+				return date, nil
+			}
+		}
+	}
+
+	return time.Time{}, ErrNoOccurrences
+}
+
+// daysInMonthMax - наибольшее количество дней в месяце с учётом високосного февраля
+var daysInMonthMax = [13]int{0, 31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+// monthlyPossible проверяет, что хотя бы один день правила существует в одном из месяцев
+func monthlyPossible(parsed *Parsed, months *[13]bool) bool {
+	if len(parsed.weekdays) > 0 {
 		return true
 	}
+
+	for month := 1; month <= 12; month++ {
+		if !months[month] {
+			continue
+		}
+		for _, day := range parsed.days {
+			if day <= daysInMonthMax[month] && day >= -daysInMonthMax[month] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// weekStart возвращает понедельник недели, в которую попадает date
+func weekStart(date time.Time) time.Time {
+	offset := (int(date.Weekday()) + 6) % 7 // понедельник -> 0, воскресенье -> 6
+	return date.AddDate(0, 0, -offset)
+}
+
+func weeksBetween(from, to time.Time) int {
+	return daysBetween(from, to) / 7
+}
+
+// validMonthlyDate проверяет, что date - один из дней месяца days: 1..31 от начала, -1..-31 от конца.
+// С clamp дни, которых нет в месяце, совпадают с последним (или первым для отрицательных) днём
+func validMonthlyDate(date time.Time, days []int, clamp bool) bool {
+	day := date.Day()
+	daysInMonth := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+
+	for _, d := range days {
+		if d < 0 {
+			d += daysInMonth + 1
+		}
+		if clamp {
+			d = min(max(d, 1), daysInMonth)
+		}
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+func monthsBetween(from, to time.Time) int {
+	return (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+}
+
+// monthlyDates возвращает подходящие под правило даты месяца month по возрастанию
+func monthlyDates(month time.Time, parsed *Parsed) []time.Time {
+	dates := make([]time.Time, 0)
+	for date := month; date.Month() == month.Month(); date = date.AddDate(0, 0, 1) {
+		if validMonthlyDate(date, parsed.days, parsed.clamp) || validMonthlyWeekday(date, parsed.weekdays) {
+			dates = append(dates, date)
+		}
+	}
+
+	if len(parsed.setPos) == 0 {
+		return dates
+	}
+
+	// BYSETPOS оставляет только даты с указанными порядковыми номерами
+	selected := make([]time.Time, 0, len(parsed.setPos))
+	for i, date := range dates {
+		for _, pos := range parsed.setPos {
+			if pos == i+1 || pos == i-len(dates) {
+				selected = append(selected, date)
+				break
+			}
+		}
+	}
+	return selected
+}
+
+func validMonthlyWeekday(date time.Time, weekdays []nthWeekday) bool {
+	day := date.Day()
+	daysInMonth := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	fromStart := (day-1)/7 + 1
+	fromEnd := -((daysInMonth-day)/7 + 1)
+
+	for _, w := range weekdays {
+		if w.weekday == date.Weekday() && (w.n == 0 || w.n == fromStart || w.n == fromEnd) {
+			return true
+		}
+	}
 	return false
 }
 
-func nextDateHandler(w http.ResponseWriter, req *http.Request) {
+// nextDatesLimit - максимальное значение параметра count в /api/nextdate
+const nextDatesLimit = 100
+
+type NextDatesResp struct {
+	Dates []string `json:"dates"`
+}
+
+// nextDateHandler возвращает дату YYYYMMDD, а если передано время задачи time -
+// дату и время через пробел: YYYYMMDD HH:MM. now принимается в тех же форматах.
+// С параметром count возвращает JSON со списком из count ближайших дат
+func (a *API) nextDateHandler(w http.ResponseWriter, req *http.Request) {
 	nowStr := req.FormValue("now")
 	date := req.FormValue("date")
+	clock := req.FormValue("time")
 	repeat := req.FormValue("repeat")
 
+	loc, err := a.requestLocation(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	var parsedNow time.Time
 	if nowStr != "" {
-		var err error
-		parsedNow, err = time.Parse(Dateformat, nowStr)
+		parsedNow, err = time.ParseInLocation(Dateformat, nowStr, loc)
+		if err != nil {
+			parsedNow, err = time.ParseInLocation(Dateformat+" "+Timeformat, nowStr, loc)
+		}
 		if err != nil {
 			http.Error(w, "incorrect now", http.StatusBadRequest)
 			return
 		}
 	} else {
-		parsedNow = time.Now() // Используем текущее время, если now не указано
+		parsedNow = a.now().In(loc) // Используем текущее время, если now не указано
+	}
+
+	// count - список ближайших дат в JSON для предпросмотра правила
+	if countStr := req.FormValue("count"); countStr != "" {
+		count, err := strconv.Atoi(countStr)
+		if err != nil || count < 1 || count > nextDatesLimit {
+			writeJSONError(w, http.StatusBadRequest, errors.New("incorrect count"))
+			return
+		}
+
+		dates, err := nextDates(parsedNow, date, clock, repeat, count, a.currentCalendar())
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, NextDatesResp{Dates: dates})
+		return
 	}
 
-	nextDate, err := NextDate(parsedNow, date, repeat)
+	nextDate, nextTime, err := nextDateTime(parsedNow, date, clock, repeat, a.currentCalendar())
 
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	if clock != "" {
+		nextDate += " " + nextTime
+	}
+
 	io.WriteString(w, nextDate)
 }
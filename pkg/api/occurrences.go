@@ -0,0 +1,181 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/ezfroze/go_final_project/pkg/db"
+)
+
+const (
+	occurrencesTasksLimit = 1000
+	occurrencesPageSize   = 100
+	occurrencesLimit      = 500
+)
+
+type Occurrence struct {
+	Date string   `json:"date"`
+	Time string   `json:"time,omitempty"`
+	Task *db.Task `json:"task"`
+}
+
+type OccurrencesResp struct {
+	Occurrences []Occurrence `json:"occurrences"`
+	// Truncated - в диапазоне больше повторений, чем occurrencesLimit,
+	// или больше задач, чем occurrencesTasksLimit
+	Truncated bool `json:"truncated"`
+}
+
+// occurrencesHandler раскрывает задачи в конкретные даты в диапазоне from..to включительно
+func (a *API) occurrencesHandler(w http.ResponseWriter, r *http.Request) {
+	from := r.FormValue("from")
+	to := r.FormValue("to")
+	search := r.FormValue("search")
+
+	if _, err := time.Parse(Dateformat, from); err != nil {
+		writeJSONError(w, http.StatusBadRequest, errors.New("incorrect from"))
+		return
+	}
+	if _, err := time.Parse(Dateformat, to); err != nil {
+		writeJSONError(w, http.StatusBadRequest, errors.New("incorrect to"))
+		return
+	}
+	if from > to {
+		writeJSONError(w, http.StatusBadRequest, errors.New("from is after to"))
+		return
+	}
+
+	now, err := a.requestNow(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	tasks, truncated, err := a.rangeTasks(from, to, search, now)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	occurrences := make([]Occurrence, 0)
+	for _, task := range tasks {
+		except, err := a.store.Exceptions(task.ID)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		expanded, err := expandTask(task, from, to, except, occurrencesLimit+1, now.Location(), a.currentCalendar())
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		occurrences = append(occurrences, expanded...)
+	}
+
+	// задачи уже упорядочены по дате, времени и id, стабильная сортировка сохраняет этот порядок
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		if occurrences[i].Date != occurrences[j].Date {
+			return occurrences[i].Date < occurrences[j].Date
+		}
+		return occurrences[i].Time < occurrences[j].Time
+	})
+
+	resp := OccurrencesResp{Occurrences: occurrences, Truncated: truncated}
+	if len(occurrences) > occurrencesLimit {
+		resp.Occurrences = occurrences[:occurrencesLimit]
+		resp.Truncated = true
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// rangeTasks возвращает задачи, у которых могут быть повторения в диапазоне from..to: задачи
+// не позже to, кроме задач без повторения раньше from. truncated - задач больше occurrencesTasksLimit
+func (a *API) rangeTasks(from, to, search string, now time.Time) ([]*db.Task, bool, error) {
+	filter, err := db.ParseFilter("date<=" + to + " (date>=" + from + " OR repeat:yes)")
+	if err != nil {
+		return nil, false, err
+	}
+
+	query := db.TaskQuery{Search: search, Filter: filter, Now: now}
+	tasks := make([]*db.Task, 0)
+	var after *db.TaskCursor
+	for {
+		page, err := a.store.TasksPage(occurrencesPageSize, query, after)
+		if err != nil {
+			return nil, false, err
+		}
+		tasks = append(tasks, page.Tasks...)
+
+		if page.Next == nil {
+			return tasks, false, nil
+		}
+		if len(tasks) >= occurrencesTasksLimit {
+			return tasks, true, nil
+		}
+		after = page.Next
+	}
+}
+
+// expandTask возвращает не больше limit повторений задачи в диапазоне from..to,
+// даты и время задачи считаются в часовом поясе loc
+func expandTask(task *db.Task, from, to string, except []string, limit int, loc *time.Location, cal Calendar) ([]Occurrence, error) {
+	occurrences := make([]Occurrence, 0)
+	current := *task
+
+	// без ограничения по количеству повторений можно сразу перейти к началу диапазона
+	if current.Repeat != "" && current.RepeatCount == 0 && current.Date < from {
+		fromDate, err := time.ParseInLocation(Dateformat, from, loc)
+		if err != nil {
+			return nil, err
+		}
+
+		current.Date, current.Time, err = nextTaskDate(fromDate.Add(-time.Second), &current, except, cal)
+		if errors.Is(err, ErrRepeatEnded) {
+			return occurrences, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// n - количество повторений, учитываемых в repeat_count
+	n := 0
+	for current.Date <= to && len(occurrences) < limit {
+		if !slices.Contains(except, current.Date) {
+			n++
+			if current.Date >= from {
+				occurrences = append(occurrences, Occurrence{Date: current.Date, Time: current.Time, Task: task})
+			}
+		}
+
+		if current.Repeat == "" || n == current.RepeatCount {
+			break
+		}
+
+		// следующее повторение ищем строго позже текущего
+		clock := current.Time
+		if clock == "" {
+			clock = "00:00"
+		}
+		now, err := time.ParseInLocation(Dateformat+Timeformat, current.Date+clock, loc)
+		if err != nil {
+			return nil, err
+		}
+
+		current.Date, current.Time, err = nextTaskDate(now, &current, except, cal)
+		if errors.Is(err, ErrRepeatEnded) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return occurrences, nil
+}
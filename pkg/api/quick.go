@@ -0,0 +1,354 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ezfroze/go_final_project/pkg/db"
+)
+
+// Части фразы, которые распознаёт быстрое добавление. Каждая часть учитывается один раз
+const (
+	quickTime = iota
+	quickRepeat
+	quickDate
+)
+
+// quickMonthDay подставляется в правило m вместо дня месяца, когда он берётся из даты задачи
+const quickMonthDay = "{day}"
+
+type quickTask struct {
+	task  db.Task
+	now   time.Time
+	start time.Time
+	found [3]bool
+}
+
+type quickRule struct {
+	part  int
+	re    *regexp.Regexp
+	apply func(q *quickTask, m []string) error
+}
+
+const (
+	enWeekday = `(?:mon(?:day)?|tue(?:s|sday)?|wed(?:nesday)?|thu(?:r|rs|rsday)?|fri(?:day)?|sat(?:urday)?|sun(?:day)?)s?`
+	ruWeekday = `(?:понедельник(?:ам|у|а)?|вторник(?:ам|у|а)?|сред(?:ам|а|у|е)|четверг(?:ам|у|а)?|` +
+		`пятниц(?:ам|а|у|е)|суббот(?:ам|а|у|е)|воскресень(?:ям|е|я)|пн|вт|ср|чт|пт|сб|вс)`
+	weekdayList = `((?:` + enWeekday + `|` + ruWeekday + `)(?:(?:\s*,\s*|\s+and\s+|\s+и\s+)(?:` + enWeekday + `|` + ruWeekday + `))*)`
+)
+
+var (
+	weekdayWordRe = regexp.MustCompile(`(?i)` + enWeekday + `|` + ruWeekday)
+
+	// префиксы названий дней недели: 1 - понедельник, 7 - воскресенье
+	weekdayPrefixes = []struct {
+		prefix string
+		day    int
+	}{
+		{"mo", 1}, {"tu", 2}, {"we", 3}, {"th", 4}, {"fr", 5}, {"sa", 6}, {"su", 7},
+		{"пн", 1}, {"по", 1}, {"вт", 2}, {"ср", 3}, {"чт", 4}, {"че", 4},
+		{"пт", 5}, {"пя", 5}, {"сб", 6}, {"су", 6}, {"вс", 7}, {"во", 7},
+	}
+)
+
+// qre добавляет к выражению границы слова: \b в regexp не работает с кириллицей
+func qre(body string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)(?:^|[\s,(])` + body + `(?:[\s,.!?;)]|$)`)
+}
+
+func setRepeat(repeat string) func(q *quickTask, m []string) error {
+	return func(q *quickTask, m []string) error {
+		q.task.Repeat = repeat
+		return nil
+	}
+}
+
+// setRepeatN подставляет число из фразы в правило: format - "d %d"
+func setRepeatN(format string, multiplier int) func(q *quickTask, m []string) error {
+	return func(q *quickTask, m []string) error {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return err
+		}
+		q.task.Repeat = fmt.Sprintf(format, n*multiplier)
+		return nil
+	}
+}
+
+// setWeekly - правило w по списку дней недели из m[1] и интервалу interval
+func setWeekly(interval int) func(q *quickTask, m []string) error {
+	return func(q *quickTask, m []string) error {
+		n := interval
+		if len(m) > 2 && m[2] != "" {
+			var err error
+			if n, err = strconv.Atoi(m[2]); err != nil {
+				return err
+			}
+		}
+
+		q.task.Repeat = WEEKDAY + " " + joinInts(quickWeekdays(m[1]))
+		if n > 1 {
+			q.task.Repeat += " " + strconv.Itoa(n)
+		}
+		return nil
+	}
+}
+
+func quickWeekdays(list string) []int {
+	days := make([]int, 0, 7)
+	for _, word := range weekdayWordRe.FindAllString(list, -1) {
+		word = strings.ToLower(word)
+		for _, p := range weekdayPrefixes {
+			if strings.HasPrefix(word, p.prefix) {
+				days = append(days, p.day)
+				break
+			}
+		}
+	}
+	return days
+}
+
+// setStartDays - дата через days дней от сегодняшнего
+func setStartDays(days int) func(q *quickTask, m []string) error {
+	return func(q *quickTask, m []string) error {
+		q.start = q.now.AddDate(0, 0, days)
+		return nil
+	}
+}
+
+func setStartIn(multiplier int) func(q *quickTask, m []string) error {
+	return func(q *quickTask, m []string) error {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return err
+		}
+		q.start = q.now.AddDate(0, 0, n*multiplier)
+		return nil
+	}
+}
+
+// setStartWeekday - ближайший день недели из m[2]: начиная с сегодняшнего,
+// а со словом next или следующий (m[1]) - начиная с завтрашнего
+func setStartWeekday(q *quickTask, m []string) error {
+	days := quickWeekdays(m[2])
+	if len(days) == 0 {
+		return errors.New("unknown weekday")
+	}
+
+	date := q.now
+	if m[1] != "" {
+		date = date.AddDate(0, 0, 1)
+	}
+	for int(date.Weekday()) != days[0]%7 {
+		date = date.AddDate(0, 0, 1)
+	}
+	q.start = date
+	return nil
+}
+
+// setStartDate - дата из m[1..3]: layout задаёт порядок частей
+func setStartDate(layout string) func(q *quickTask, m []string) error {
+	return func(q *quickTask, m []string) error {
+		date, err := time.ParseInLocation(layout, m[1], q.now.Location())
+		if err != nil {
+			return errors.New("incorrect date")
+		}
+		q.start = date
+		return nil
+	}
+}
+
+func setTime(q *quickTask, m []string) error {
+	clock, err := time.Parse(Timeformat, m[1]+":"+m[2])
+	if err != nil {
+		return errors.New("incorrect time")
+	}
+	q.task.Time = clock.Format(Timeformat)
+	return nil
+}
+
+const (
+	startWord = `(?:(?:starting|from|beginning|начиная|с|со)\s+)?(?:(?:on|с|со|в|во)\s+)?`
+	dayWords  = `(?:день|дня|дней)`
+)
+
+// quickRules проверяются по порядку: более точные фразы раньше общих
+var quickRules = []quickRule{
+	{quickTime, qre(`(?:at|в)\s+(\d{1,2}):(\d{2})`), setTime},
+	{quickTime, qre(`(\d{1,2}):(\d{2})`), setTime},
+
+	{quickRepeat, qre(`every\s+(\d+)\s+(?:business|working|work)\s+days`), setRepeatN(BUSINESSDAY+" %d", 1)},
+	{quickRepeat, qre(`every\s+(?:business|working|work)\s+day`), setRepeat(BUSINESSDAY + " 1")},
+	{quickRepeat, qre(`кажд(?:ые|ый)\s+(\d+)\s+рабочи[хй]\s+` + dayWords), setRepeatN(BUSINESSDAY+" %d", 1)},
+	{quickRepeat, qre(`каждый\s+рабочий\s+день`), setRepeat(BUSINESSDAY + " 1")},
+	{quickRepeat, qre(`(?:every\s+weekday|по\s+будням|каждый\s+будний\s+день)`), setRepeat(WEEKDAY + " 1,2,3,4,5")},
+
+	{quickRepeat, qre(`every\s+(\d+)\s+hours?`), setRepeatN(HOUR+" %d", 1)},
+	{quickRepeat, qre(`(?:every\s+hour|hourly|каждый\s+час|ежечасно)`), setRepeat(HOUR + " 1")},
+	{quickRepeat, qre(`кажд(?:ые|ый)\s+(\d+)\s+час(?:а|ов)?`), setRepeatN(HOUR+" %d", 1)},
+
+	{quickRepeat, qre(`every\s+(\d+)\s+days?`), setRepeatN(DAY+" %d", 1)},
+	{quickRepeat, qre(`(?:every\s+day|daily|каждый\s+день|ежедневно)`), setRepeat(DAY + " 1")},
+	{quickRepeat, qre(`(?:every\s+other\s+day|через\s+день)`), setRepeat(DAY + " 2")},
+	{quickRepeat, qre(`кажд(?:ые|ый)\s+(\d+)\s+` + dayWords), setRepeatN(DAY+" %d", 1)},
+
+	{quickRepeat, qre(`every\s+(?:(other)|(\d+))\s+weeks?\s+on\s+` + weekdayList), func(q *quickTask, m []string) error {
+		interval := "2"
+		if m[1] == "" {
+			interval = m[2]
+		}
+		return setWeekly(1)(q, []string{m[0], m[3], interval})
+	}},
+	{quickRepeat, qre(`кажд(?:ые|ую)\s+(\d+)\s+недел[иь]\s+(?:по|в|во)\s+` + weekdayList), func(q *quickTask, m []string) error {
+		return setWeekly(1)(q, []string{m[0], m[2], m[1]})
+	}},
+	{quickRepeat, qre(`(?:every|кажд(?:ый|ую|ое)|по)\s+` + weekdayList), setWeekly(1)},
+	{quickRepeat, qre(`every\s+(\d+)\s+weeks?`), setRepeatN(DAY+" %d", 7)},
+	{quickRepeat, qre(`кажд(?:ые|ую)\s+(\d+)\s+недел[иью]`), setRepeatN(DAY+" %d", 7)},
+	{quickRepeat, qre(`(?:every\s+week|weekly|каждую\s+неделю|еженедельно)`), setRepeat(DAY + " 7")},
+
+	{quickRepeat, qre(`(?:every|each)\s+month\s+on\s+the\s+(\d{1,2})(?:st|nd|rd|th)?`), setRepeatN(MONTH+" %d", 1)},
+	{quickRepeat, qre(`on\s+the\s+(\d{1,2})(?:st|nd|rd|th)?\s+of\s+(?:every|each)\s+month`), setRepeatN(MONTH+" %d", 1)},
+	{quickRepeat, qre(`(?:on\s+the\s+last\s+day\s+of\s+(?:every|each)\s+month|every\s+month\s+on\s+the\s+last\s+day)`),
+		setRepeat(MONTH + " -1")},
+	{quickRepeat, qre(`каждый\s+месяц\s+(\d{1,2})(?:-?го)?\s+числа`), setRepeatN(MONTH+" %d", 1)},
+	{quickRepeat, qre(`(\d{1,2})(?:-?го)?\s+числа\s+каждого\s+месяца`), setRepeatN(MONTH+" %d", 1)},
+	{quickRepeat, qre(`(?:в\s+)?последний\s+день\s+(?:каждого\s+)?месяца`), setRepeat(MONTH + " -1")},
+	{quickRepeat, qre(`every\s+(\d+)\s+months?`), setRepeatN("FREQ=MONTHLY;INTERVAL=%d", 1)},
+	{quickRepeat, qre(`кажд(?:ые|ый)\s+(\d+)\s+месяц(?:а|ев)?`), setRepeatN("FREQ=MONTHLY;INTERVAL=%d", 1)},
+	{quickRepeat, qre(`(?:every\s+month|monthly|каждый\s+месяц|ежемесячно)`), setRepeat(MONTH + " " + quickMonthDay)},
+
+	{quickRepeat, qre(`every\s+(\d+)\s+years?`), setRepeatN(YEAR+" %d", 1)},
+	{quickRepeat, qre(`кажд(?:ые|ый)\s+(\d+)\s+(?:год|года|лет)`), setRepeatN(YEAR+" %d", 1)},
+	{quickRepeat, qre(`(?:every\s+year|yearly|annually|каждый\s+год|ежегодно)`), setRepeat(YEAR)},
+
+	{quickDate, qre(startWord + `(today|сегодня|сегодняшнего\s+дня)`), setStartDays(0)},
+	{quickDate, qre(startWord + `(day\s+after\s+tomorrow|послезавтра)`), setStartDays(2)},
+	{quickDate, qre(startWord + `(tomorrow|завтра|завтрашнего\s+дня)`), setStartDays(1)},
+	{quickDate, qre(`(?:in|через)\s+(\d+)\s+(?:days?|` + dayWords + `)`), setStartIn(1)},
+	{quickDate, qre(`(?:in|через)\s+(\d+)\s+(?:weeks?|недел[юиь])`), setStartIn(7)},
+	{quickDate, qre(startWord + `(\d{1,2}\.\d{1,2}\.\d{4})`), setStartDate("2.1.2006")},
+	{quickDate, qre(startWord + `(\d{4}-\d{2}-\d{2})`), setStartDate("2006-01-02")},
+	{quickDate, qre(startWord + `(next\s+|следующ(?:ий|ую|ее|его|ей)\s+)?(` + enWeekday + `|` + ruWeekday + `)`), setStartWeekday},
+}
+
+// ParseQuickTask разбирает фразу вроде "pay rent every month on the 5th starting next Monday"
+// или "звонок маме каждую среду" в задачу: распознанные дата, время и повторение
+// убираются из текста, остаток становится заголовком. now - текущий момент пользователя
+func ParseQuickTask(text string, now time.Time) (*db.Task, error) {
+	q := &quickTask{now: now}
+	rest := " " + strings.Join(strings.Fields(text), " ") + " "
+
+	for _, rule := range quickRules {
+		if q.found[rule.part] {
+			continue
+		}
+
+		loc := rule.re.FindStringSubmatchIndex(rest)
+		if loc == nil {
+			continue
+		}
+
+		m := make([]string, len(loc)/2)
+		for i := range m {
+			if loc[2*i] >= 0 {
+				m[i] = rest[loc[2*i]:loc[2*i+1]]
+			}
+		}
+		if err := rule.apply(q, m); err != nil {
+			return nil, err
+		}
+
+		q.found[rule.part] = true
+		rest = rest[:loc[0]] + " " + rest[loc[1]:]
+	}
+
+	q.task.Title = strings.Trim(strings.Join(strings.Fields(rest), " "), " ,.;:-")
+	if q.task.Title == "" {
+		return nil, errors.New("title is required")
+	}
+
+	start := q.start
+	if start.IsZero() {
+		start = now
+	}
+	q.task.Date = start.Format(Dateformat)
+
+	if q.task.Repeat == "" {
+		return &q.task, nil
+	}
+	q.task.Repeat = strings.Replace(q.task.Repeat, quickMonthDay, strconv.Itoa(start.Day()), 1)
+
+	// у правила h должно быть время: берём ближайший полный час
+	if isHourly(q.task.Repeat) && q.task.Time == "" {
+		q.task.Time = now.Truncate(time.Hour).Add(time.Hour).Format(Timeformat)
+	}
+
+	// правила w и m начинаются с первого подходящего дня, не раньше даты начала
+	parsed, err := parseRepeat(q.task.Repeat)
+	if err == nil && (parsed.rType == WEEKDAY || parsed.rType == MONTH) {
+		day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, now.Location())
+		if next, err := NextDate(day.Add(-time.Second), q.task.Date, q.task.Repeat); err == nil {
+			q.task.Date = next
+		}
+	}
+
+	return &q.task, nil
+}
+
+type QuickTaskReq struct {
+	Text string `json:"text"`
+}
+
+// quickTaskHandler добавляет задачу, описанную фразой: {"text": "звонок маме каждую среду"}.
+// Возвращает созданную задачу
+func (a *API) quickTaskHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	if r.Body == nil {
+		writeJSONError(w, http.StatusBadRequest, errors.New("empty request body"))
+		return
+	}
+
+	var req QuickTaskReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	now, err := a.requestNow(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	task, err := ParseQuickTask(req.Text, now)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := a.checkDate(task, now); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	id, err := a.store.AddTask(task)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	task.ID = strconv.FormatInt(id, 10)
+	fillRepeat(task, requestLang(r))
+	writeJSON(w, http.StatusOK, task)
+}
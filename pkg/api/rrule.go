@@ -0,0 +1,537 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Поддерживается подмножество RFC 5545:
+// FREQ (кроме SECONDLY и MINUTELY), INTERVAL, BYDAY, BYMONTHDAY, BYMONTH, BYSETPOS, COUNT, UNTIL
+// example - FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE; RRULE:FREQ=MONTHLY;BYDAY=-1FR;BYMONTH=3,9
+
+const rrulePrefix = "RRULE:"
+
+// rruleMaxCount - наибольший COUNT. Серия с COUNT обходится от даты начала по одному повторению,
+// поэтому COUNT ограничивает стоимость расчёта следующей даты
+const rruleMaxCount = 10000
+
+var rruleWeekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+func isRRule(repeat string) bool {
+	upper := strings.ToUpper(repeat)
+	return strings.HasPrefix(upper, rrulePrefix) || strings.HasPrefix(upper, "FREQ=")
+}
+
+func parseRRule(rule string) (*Parsed, error) {
+	result := &Parsed{interval: 1}
+
+	rule = strings.TrimSpace(rule)
+	if strings.HasPrefix(strings.ToUpper(rule), rrulePrefix) {
+		rule = rule[len(rrulePrefix):]
+	}
+
+	parts := make(map[string]string)
+	for _, part := range strings.Split(strings.ToUpper(rule), ";") {
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return result, fmt.Errorf("invalid rrule part %q", part)
+		}
+		if _, exists := parts[name]; exists {
+			return result, fmt.Errorf("duplicate rrule part %s", name)
+		}
+		parts[name] = value
+	}
+
+	for name, value := range parts {
+		switch name {
+		case "FREQ", "INTERVAL", "BYDAY", "BYMONTHDAY", "BYMONTH", "BYSETPOS":
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 1 || count > rruleMaxCount {
+				return result, errors.New("invalid rrule COUNT")
+			}
+			result.count = count
+		case "UNTIL":
+			until, err := parseRRuleDate(value)
+			if err != nil {
+				return result, errors.New("invalid rrule UNTIL")
+			}
+			result.until = until
+		case "WKST":
+			if value != "MO" {
+				return result, errors.New("only WKST=MO is supported")
+			}
+		default:
+			return result, fmt.Errorf("unsupported rrule part %s", name)
+		}
+	}
+
+	if result.count > 0 && result.until != "" {
+		return result, errors.New("rrule COUNT and UNTIL are mutually exclusive")
+	}
+
+	if value, ok := parts["INTERVAL"]; ok {
+		interval, err := strconv.Atoi(value)
+		if err != nil || interval < 1 {
+			return result, errors.New("invalid rrule INTERVAL")
+		}
+		result.interval = interval
+	}
+
+	var (
+		weekdays []nthWeekday
+		err      error
+	)
+	if value, ok := parts["BYDAY"]; ok {
+		weekdays, err = parseRRuleWeekdays(value)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	var monthDays []int
+	if value, ok := parts["BYMONTHDAY"]; ok {
+		monthDays, err = parseRRuleInts(value, "BYMONTHDAY", func(n int) bool {
+			return (n >= 1 && n <= 31) || (n >= -31 && n <= -1)
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+
+	var months []int
+	if value, ok := parts["BYMONTH"]; ok {
+		months, err = parseRRuleInts(value, "BYMONTH", func(n int) bool { return n >= 1 && n <= 12 })
+		if err != nil {
+			return result, err
+		}
+	}
+
+	var setPos []int
+	if value, ok := parts["BYSETPOS"]; ok {
+		setPos, err = parseRRuleInts(value, "BYSETPOS", func(n int) bool { return n != 0 && n >= -31 && n <= 31 })
+		if err != nil {
+			return result, err
+		}
+	}
+
+	freq, ok := parts["FREQ"]
+	if !ok {
+		return result, errors.New("rrule FREQ is required")
+	}
+
+	unsupported := fmt.Errorf("unsupported rrule combination for FREQ=%s", freq)
+
+	switch freq {
+	case "HOURLY":
+		if len(weekdays) > 0 || len(monthDays) > 0 || len(months) > 0 || len(setPos) > 0 {
+			return result, unsupported
+		}
+		if result.interval > 168 {
+			return result, errors.New("invalid rrule INTERVAL")
+		}
+		result.rType = HOUR
+
+	case "DAILY":
+		if len(monthDays) > 0 || len(months) > 0 || len(setPos) > 0 {
+			return result, unsupported
+		}
+		if len(weekdays) == 0 {
+			if result.interval > 400 {
+				return result, errors.New("invalid rrule INTERVAL")
+			}
+			result.rType = DAY
+			result.days = []int{result.interval}
+			result.interval = 1
+			break
+		}
+		// FREQ=DAILY;BYDAY=MO,FR - то же, что еженедельное правило
+		if result.interval != 1 {
+			return result, unsupported
+		}
+		result.rType = WEEKDAY
+		result.days, err = plainWeekdays(weekdays)
+		if err != nil {
+			return result, err
+		}
+
+	case "WEEKLY":
+		if len(monthDays) > 0 || len(months) > 0 || len(setPos) > 0 {
+			return result, unsupported
+		}
+		if result.interval > 52 {
+			return result, errors.New("invalid rrule INTERVAL")
+		}
+		result.rType = WEEKDAY
+		result.days, err = plainWeekdays(weekdays)
+		if err != nil {
+			return result, err
+		}
+
+	case "MONTHLY":
+		// в RFC 5545 BYDAY и BYMONTHDAY пересекаются, а правило m их объединяет
+		if len(monthDays) > 0 && len(weekdays) > 0 {
+			return result, unsupported
+		}
+		if result.interval > 1200 {
+			return result, errors.New("invalid rrule INTERVAL")
+		}
+		result.rType = MONTH
+		result.days = monthDays
+		result.weekdays = weekdays
+		result.months = months
+		result.setPos = setPos
+
+	case "YEARLY":
+		if len(setPos) > 0 {
+			return result, unsupported
+		}
+		if result.interval > 100 {
+			return result, errors.New("invalid rrule INTERVAL")
+		}
+		if len(monthDays) == 0 && len(weekdays) == 0 && len(months) == 0 {
+			result.rType = YEAR
+			break
+		}
+		// FREQ=YEARLY;INTERVAL=2;BYMONTH=12;BYMONTHDAY=25 - то же, что y 25.12 2
+		if result.interval != 1 {
+			yearDays, ok := rruleYearDays(monthDays, months)
+			if !ok || len(weekdays) > 0 {
+				return result, unsupported
+			}
+			result.rType = YEAR
+			result.yearDays = yearDays
+			break
+		}
+		// FREQ=YEARLY;BYMONTH=12;BYMONTHDAY=25 - то же, что m 25 12
+		if len(months) == 0 || (len(monthDays) > 0 && len(weekdays) > 0) {
+			return result, unsupported
+		}
+		result.rType = MONTH
+		result.days = monthDays
+		result.weekdays = weekdays
+		result.months = months
+
+	default:
+		return result, fmt.Errorf("unsupported rrule FREQ=%s", freq)
+	}
+
+	return result, nil
+}
+
+// parseRRuleDate принимает даты UNTIL в форматах YYYYMMDD и YYYYMMDDTHHMMSS[Z]
+func parseRRuleDate(value string) (string, error) {
+	date, _, _ := strings.Cut(value, "T")
+	if _, err := time.Parse(Dateformat, date); err != nil {
+		return "", err
+	}
+	return date, nil
+}
+
+func parseRRuleInts(value, name string, valid func(int) bool) ([]int, error) {
+	items := strings.Split(value, ",")
+	result := make([]int, 0, len(items))
+	for _, item := range items {
+		n, err := strconv.Atoi(item)
+		if err != nil || !valid(n) {
+			return nil, fmt.Errorf("invalid rrule %s", name)
+		}
+		result = append(result, n)
+	}
+	return result, nil
+}
+
+// parseRRuleWeekdays разбирает BYDAY: MO,TU или с порядковым номером 2TU,-1FR.
+// n == 0 означает день недели без номера
+func parseRRuleWeekdays(value string) ([]nthWeekday, error) {
+	items := strings.Split(value, ",")
+	result := make([]nthWeekday, 0, len(items))
+	for _, item := range items {
+		if len(item) < 2 {
+			return nil, errors.New("invalid rrule BYDAY")
+		}
+		weekday, ok := rruleWeekdays[item[len(item)-2:]]
+		if !ok {
+			return nil, errors.New("invalid rrule BYDAY")
+		}
+
+		n := 0
+		if prefix := item[:len(item)-2]; prefix != "" {
+			var err error
+			n, err = strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, errors.New("invalid rrule BYDAY")
+			}
+		}
+		result = append(result, nthWeekday{n: n, weekday: weekday})
+	}
+	return result, nil
+}
+
+// plainWeekdays переводит BYDAY без номеров в дни недели правила w: 1 - понедельник, 7 - воскресенье
+func plainWeekdays(weekdays []nthWeekday) ([]int, error) {
+	days := make([]int, 0, len(weekdays))
+	for _, w := range weekdays {
+		if w.n != 0 {
+			return nil, errors.New("invalid rrule BYDAY")
+		}
+		day := int(w.weekday)
+		if day == 0 {
+			day = 7
+		}
+		if !slices.Contains(days, day) {
+			days = append(days, day)
+		}
+	}
+	return days, nil
+}
+
+// formatRepeat записывает правило в формате d/w/m/y.
+// Возвращает false, если правило нельзя выразить в этом формате
+func formatRepeat(parsed *Parsed) (string, bool) {
+	if parsed.count > 0 || parsed.until != "" || len(parsed.setPos) > 0 {
+		return "", false
+	}
+
+	var roll string
+	switch parsed.roll {
+	case 1:
+		roll = " " + rollForward
+	case -1:
+		roll = " " + rollBackward
+	}
+	if parsed.clamp {
+		roll = " " + clampDays + roll
+	}
+
+	switch parsed.rType {
+	case HOUR:
+		return fmt.Sprintf("%s %d", HOUR, parsed.interval), true
+	case BUSINESSDAY:
+		return fmt.Sprintf("%s %d", BUSINESSDAY, parsed.interval), true
+	case DAY:
+		return fmt.Sprintf("%s %d", DAY, parsed.days[0]) + roll, true
+	case YEAR:
+		repeat := YEAR
+		if len(parsed.yearDays) > 0 {
+			days := make([]string, 0, len(parsed.yearDays))
+			for _, d := range parsed.yearDays {
+				days = append(days, fmt.Sprintf("%02d.%02d", d.day, d.month))
+			}
+			repeat += " " + strings.Join(days, ",")
+		}
+		if parsed.interval > 1 {
+			repeat += " " + strconv.Itoa(parsed.interval)
+		}
+		return repeat + roll, true
+	case WEEKDAY:
+		if len(parsed.days) == 0 {
+			return "", false
+		}
+		repeat := WEEKDAY + " " + joinInts(parsed.days)
+		if parsed.interval > 1 {
+			repeat += " " + strconv.Itoa(parsed.interval)
+		}
+		return repeat + roll, true
+	case MONTH:
+		if parsed.interval > 1 || (len(parsed.days) == 0 && len(parsed.weekdays) == 0) {
+			return "", false
+		}
+		days := make([]string, 0, len(parsed.days)+len(parsed.weekdays))
+		for _, day := range parsed.days {
+			days = append(days, strconv.Itoa(day))
+		}
+		for _, w := range parsed.weekdays {
+			if w.n == 0 {
+				return "", false
+			}
+			days = append(days, strconv.Itoa(w.n)+weekdayName(w.weekday))
+		}
+		repeat := MONTH + " " + strings.Join(days, ",")
+		if len(parsed.months) > 0 {
+			repeat += " " + joinInts(parsed.months)
+		}
+		return repeat + roll, true
+	}
+
+	return "", false
+}
+
+// formatRRule записывает правило в формате RRULE.
+// Возвращает false, если правило нельзя выразить в этом формате
+func formatRRule(parsed *Parsed) (string, bool) {
+	// в RRULE нет рабочих дней, переноса дат и дней, которых нет в месяце
+	if parsed.rType == BUSINESSDAY || parsed.roll != 0 || parsed.clamp {
+		return "", false
+	}
+
+	parts := make([]string, 0, 6)
+
+	switch parsed.rType {
+	case HOUR:
+		parts = append(parts, "FREQ=HOURLY")
+		if parsed.interval > 1 {
+			parts = append(parts, "INTERVAL="+strconv.Itoa(parsed.interval))
+		}
+	case DAY:
+		parts = append(parts, "FREQ=DAILY")
+		if parsed.days[0] > 1 {
+			parts = append(parts, "INTERVAL="+strconv.Itoa(parsed.days[0]))
+		}
+	case YEAR:
+		parts = append(parts, "FREQ=YEARLY")
+		if parsed.interval > 1 {
+			parts = append(parts, "INTERVAL="+strconv.Itoa(parsed.interval))
+		}
+		if len(parsed.yearDays) > 0 {
+			months, days, ok := yearDaysGrid(parsed.yearDays)
+			if !ok {
+				return "", false
+			}
+			parts = append(parts, "BYMONTH="+joinInts(months), "BYMONTHDAY="+joinInts(days))
+		}
+	case WEEKDAY:
+		parts = append(parts, "FREQ=WEEKLY")
+		if parsed.interval > 1 {
+			parts = append(parts, "INTERVAL="+strconv.Itoa(parsed.interval))
+		}
+		if len(parsed.days) > 0 {
+			days := make([]string, 0, len(parsed.days))
+			for _, day := range parsed.days {
+				days = append(days, strings.ToUpper(weekdayName(time.Weekday(day%7))))
+			}
+			parts = append(parts, "BYDAY="+strings.Join(days, ","))
+		}
+	case MONTH:
+		if len(parsed.days) > 0 && len(parsed.weekdays) > 0 {
+			return "", false
+		}
+		parts = append(parts, "FREQ=MONTHLY")
+		if parsed.interval > 1 {
+			parts = append(parts, "INTERVAL="+strconv.Itoa(parsed.interval))
+		}
+		if len(parsed.weekdays) > 0 {
+			days := make([]string, 0, len(parsed.weekdays))
+			for _, w := range parsed.weekdays {
+				day := strings.ToUpper(weekdayName(w.weekday))
+				if w.n != 0 {
+					day = strconv.Itoa(w.n) + day
+				}
+				days = append(days, day)
+			}
+			parts = append(parts, "BYDAY="+strings.Join(days, ","))
+		}
+		if len(parsed.days) > 0 {
+			parts = append(parts, "BYMONTHDAY="+joinInts(parsed.days))
+		}
+		if len(parsed.months) > 0 {
+			parts = append(parts, "BYMONTH="+joinInts(parsed.months))
+		}
+		if len(parsed.setPos) > 0 {
+			parts = append(parts, "BYSETPOS="+joinInts(parsed.setPos))
+		}
+	}
+
+	if parsed.count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(parsed.count))
+	}
+	if parsed.until != "" {
+		parts = append(parts, "UNTIL="+parsed.until)
+	}
+
+	return strings.Join(parts, ";"), true
+}
+
+// rruleYearDays переводит BYMONTH и BYMONTHDAY в дни года правила y.
+// 29 февраля не подходит: в RRULE оно пропускается в невисокосные годы, а в y переносится
+func rruleYearDays(monthDays, months []int) ([]yearDay, bool) {
+	if len(monthDays) == 0 || len(months) == 0 {
+		return nil, false
+	}
+	yearDays := make([]yearDay, 0, len(monthDays)*len(months))
+	for _, month := range months {
+		for _, day := range monthDays {
+			if day < 1 || day > daysInMonthMax[month] || (month == 2 && day == 29) {
+				return nil, false
+			}
+			yearDays = append(yearDays, yearDay{day: day, month: month})
+		}
+	}
+	return yearDays, true
+}
+
+// yearDaysGrid представляет дни года как BYMONTH x BYMONTHDAY.
+// Возвращает false, если дни не образуют такую сетку или среди них есть 29 февраля
+func yearDaysGrid(yearDays []yearDay) ([]int, []int, bool) {
+	var months, days []int
+	pairs := make(map[yearDay]bool, len(yearDays))
+	for _, d := range yearDays {
+		if d.month == 2 && d.day == 29 {
+			return nil, nil, false
+		}
+		if !slices.Contains(months, d.month) {
+			months = append(months, d.month)
+		}
+		if !slices.Contains(days, d.day) {
+			days = append(days, d.day)
+		}
+		pairs[d] = true
+	}
+	return months, days, len(pairs) == len(months)*len(days)
+}
+
+func weekdayName(weekday time.Weekday) string {
+	for name, w := range weekdayNames {
+		if w == weekday {
+			return name
+		}
+	}
+	return ""
+}
+
+func joinInts(values []int) string {
+	items := make([]string, 0, len(values))
+	for _, v := range values {
+		items = append(items, strconv.Itoa(v))
+	}
+	return strings.Join(items, ",")
+}
+
+type RepeatConvertResp struct {
+	Repeat string `json:"repeat"`
+	RRule  string `json:"rrule"`
+}
+
+// repeatConvertHandler переводит правило из формата d/w/m/y в RRULE и обратно.
+// Пустое поле означает, что правило нельзя выразить в этом формате
+func repeatConvertHandler(w http.ResponseWriter, r *http.Request) {
+	repeat := strings.TrimSpace(r.FormValue("repeat"))
+
+	parsed, err := parseRepeat(repeat)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var resp RepeatConvertResp
+	resp.Repeat, _ = formatRepeat(parsed)
+	resp.RRule, _ = formatRRule(parsed)
+
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/ezfroze/go_final_project/pkg/db"
+)
+
+// RuleFromRepeat переводит правило повторения из строки d/w/m/y или RRULE в структуру
+func RuleFromRepeat(repeat string) (*db.RepeatRule, error) {
+	parsed, err := parseRepeat(repeat)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := &db.RepeatRule{
+		Type:     parsed.rType,
+		Interval: parsed.interval,
+		Clamp:    parsed.clamp,
+	}
+
+	if parsed.rType == DAY {
+		rule.Interval = parsed.days[0]
+	}
+
+	if len(parsed.days) > 0 && parsed.rType != DAY {
+		rule.Days = slices.Clone(parsed.days)
+	}
+	if len(parsed.months) > 0 {
+		rule.Months = slices.Clone(parsed.months)
+	}
+	for _, w := range parsed.weekdays {
+		weekday := int(w.weekday)
+		if weekday == 0 {
+			weekday = 7
+		}
+		rule.Ordinals = append(rule.Ordinals, db.RepeatOrdinal{N: w.n, Weekday: weekday})
+	}
+	if len(parsed.setPos) > 0 {
+		rule.SetPos = slices.Clone(parsed.setPos)
+	}
+	for _, d := range parsed.yearDays {
+		rule.Dates = append(rule.Dates, db.RepeatYearDay{Day: d.day, Month: d.month})
+	}
+
+	switch parsed.roll {
+	case 1:
+		rule.Roll = rollForward
+	case -1:
+		rule.Roll = rollBackward
+	}
+
+	if parsed.count > 0 || parsed.until != "" {
+		rule.End = &db.RepeatEnd{Until: parsed.until, Count: parsed.count}
+	}
+
+	return rule, nil
+}
+
+// RepeatFromRule записывает структуру правила строкой: в формате d/w/m/y, если это возможно,
+// иначе в формате RRULE. Правило проверяется так же, как строка repeat
+func RepeatFromRule(rule *db.RepeatRule) (string, error) {
+	if rule == nil {
+		return "", errors.New("repeat rule is empty")
+	}
+	if !slices.Contains(repeatTypes, rule.Type) {
+		return "", errors.New("invalid repeat type")
+	}
+
+	interval := rule.Interval
+	if interval == 0 {
+		interval = 1
+	}
+	if interval < 0 {
+		return "", errors.New("invalid repeat interval")
+	}
+
+	parsed := &Parsed{
+		rType:    rule.Type,
+		interval: interval,
+		days:     rule.Days,
+		months:   rule.Months,
+		setPos:   rule.SetPos,
+		clamp:    rule.Clamp,
+	}
+
+	if rule.Type == DAY {
+		if len(rule.Days) > 0 {
+			return "", errors.New("days are not supported for this repeat type")
+		}
+		parsed.days = []int{interval}
+		parsed.interval = 1
+	}
+
+	if len(rule.Days) > 0 && rule.Type != WEEKDAY && rule.Type != MONTH {
+		return "", errors.New("days are not supported for this repeat type")
+	}
+	if (len(rule.Months) > 0 || len(rule.Ordinals) > 0 || len(rule.SetPos) > 0) && rule.Type != MONTH {
+		return "", errors.New("months and ordinals are supported only for month repeat")
+	}
+	if len(rule.Dates) > 0 && rule.Type != YEAR {
+		return "", errors.New("dates are supported only for year repeat")
+	}
+	if rule.Clamp && rule.Type != MONTH && rule.Type != YEAR {
+		return "", errors.New("clamp is supported only for month and year repeat")
+	}
+
+	for _, d := range rule.Dates {
+		parsed.yearDays = append(parsed.yearDays, yearDay{day: d.Day, month: d.Month})
+	}
+
+	for _, o := range rule.Ordinals {
+		if o.Weekday < 1 || o.Weekday > 7 {
+			return "", errors.New("invalid ordinal weekday")
+		}
+		parsed.weekdays = append(parsed.weekdays, nthWeekday{n: o.N, weekday: time.Weekday(o.Weekday % 7)})
+	}
+
+	switch rule.Roll {
+	case "":
+	case rollForward:
+		parsed.roll = 1
+	case rollBackward:
+		parsed.roll = -1
+	default:
+		return "", errors.New("invalid roll")
+	}
+	if parsed.roll != 0 && (rule.Type == HOUR || rule.Type == BUSINESSDAY) {
+		return "", errors.New("roll is not supported for this repeat type")
+	}
+
+	if rule.End != nil {
+		if rule.End.Count < 0 {
+			return "", errors.New("invalid repeat count")
+		}
+		parsed.count = rule.End.Count
+		parsed.until = rule.End.Until
+	}
+
+	repeat, ok := formatRepeat(parsed)
+	if !ok {
+		repeat, ok = formatRRule(parsed)
+	}
+	if !ok {
+		return "", errors.New("repeat rule cannot be expressed")
+	}
+
+	// строка проверяется разбором: так у структуры те же ограничения, что и у repeat
+	if _, err := parseRepeat(repeat); err != nil {
+		return "", err
+	}
+
+	return repeat, nil
+}
+
+// checkRepeatRule переносит правило из repeat_rule в repeat. Если заданы оба поля,
+// они должны описывать одно и то же правило
+func checkRepeatRule(task *db.Task) error {
+	if task.RepeatRule == nil {
+		return nil
+	}
+
+	repeat, err := RepeatFromRule(task.RepeatRule)
+	if err != nil {
+		return err
+	}
+
+	if task.Repeat != "" {
+		rule, err := RuleFromRepeat(task.Repeat)
+		if err != nil {
+			return err
+		}
+		same, err := RepeatFromRule(rule)
+		if err != nil || same != repeat {
+			return errors.New("repeat and repeat_rule do not match")
+		}
+	}
+
+	task.Repeat = repeat
+	task.RepeatRule = nil
+	return nil
+}
+
+// fillRepeat заполняет описание и структуру правила повторения задачи для ответа
+func fillRepeat(task *db.Task, lang string) {
+	if task.Repeat == "" {
+		return
+	}
+	task.RepeatDescription, _ = DescribeRepeat(task.Repeat, lang)
+	task.RepeatRule, _ = RuleFromRepeat(task.Repeat)
+}
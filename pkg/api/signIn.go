@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
-	"os"
 
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -15,7 +14,7 @@ type RequestBody struct {
 
 const hmacSampleSecret = "my_secret_key"
 
-func signInHandler(w http.ResponseWriter, r *http.Request) {
+func (a *API) signInHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Body == nil {
 		writeJSONError(w, http.StatusBadRequest, errors.New("empty request body"))
 		return
@@ -32,14 +31,12 @@ func signInHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	password := os.Getenv("TODO_PASSWORD")
-
-	if password == "" {
+	if a.password == "" {
 		writeJSONError(w, http.StatusBadRequest, errors.New("not set TODO_PASSWORD"))
 		return
 	}
 
-	if password != requestBody.Password {
+	if a.password != requestBody.Password {
 		writeJSONError(w, http.StatusUnauthorized, errors.New("wrong password"))
 		return
 	}
@@ -60,11 +57,10 @@ func signInHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func auth(next http.HandlerFunc) http.HandlerFunc {
+func (a *API) auth(next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// смотрим наличие пароля
-		pass := os.Getenv("TODO_PASSWORD")
-		if len(pass) > 0 {
+		if len(a.password) > 0 {
 			var jwtToken string // JWT-токен из куки
 			// получаем куку
 			cookie, err := r.Cookie("token")
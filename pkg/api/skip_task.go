@@ -0,0 +1,83 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// skipTaskHandler пропускает текущее повторение задачи: дата добавляется в исключения,
+// а задача переносится на следующую дату. Счётчик повторений не меняется
+func (a *API) skipTaskHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.FormValue("id")
+
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, errors.New("id is required"))
+		return
+	}
+
+	task, err := a.store.GetTask(id)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if task.Repeat == "" {
+		writeJSONError(w, http.StatusBadRequest, errors.New("task is not repeating"))
+		return
+	}
+
+	except, err := a.store.Exceptions(id)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// исключения хранятся по дням, поэтому у задачи с правилом h пропускается
+	// только текущее время, а не весь день
+	hourly := isHourly(task.Repeat)
+	if !hourly {
+		except = append(except, task.Date)
+	}
+
+	// следующая дата должна быть позже и текущего момента, и пропускаемого повторения
+	now, err := a.requestNow(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	clock := task.Time
+	if clock == "" {
+		clock = "00:00"
+	}
+	taskStart, err := time.ParseInLocation(Dateformat+Timeformat, task.Date+clock, now.Location())
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if taskStart.After(now) {
+		now = taskStart
+	}
+
+	nextDate, nextTime, err := nextTaskDate(now, task, except, a.currentCalendar())
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !hourly {
+		err = a.store.AddException(id, task.Date)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	err = a.store.UpdateTaskDate(id, nextDate, nextTime)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"date": nextDate, "time": nextTime})
+}
@@ -1,27 +1,101 @@
 package api
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/ezfroze/go_final_project/pkg/db"
 )
 
 type TasksResp struct {
 	Tasks []*db.Task `json:"tasks"`
+	// NextCursor - курсор следующей страницы для параметра cursor, пусто - страница последняя
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Total - количество всех найденных задач на всех страницах
+	Total int `json:"total"`
 }
 
-const tasksLimit = 50
+// Размер страницы /api/tasks: по умолчанию и наибольший
+const (
+	tasksLimit    = 50
+	tasksMaxLimit = 500
+)
 
-func tasksHandler(w http.ResponseWriter, r *http.Request) {
+// tasksHandler возвращает страницу задач. filter - выражение фильтра, limit - размер страницы,
+// cursor - курсор из next_cursor предыдущей страницы
+func (a *API) tasksHandler(w http.ResponseWriter, r *http.Request) {
 	search := r.FormValue("search")
 
-	tasks, err := db.Tasks(tasksLimit, search) // в параметре максимальное количество записей
+	now, err := a.requestNow(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	limit := tasksLimit
+	if limitStr := r.FormValue("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > tasksMaxLimit {
+			writeJSONError(w, http.StatusBadRequest, errors.New("incorrect limit"))
+			return
+		}
+	}
+
+	// фильтр берётся только из строки запроса, не из тела формы
+	filter, err := db.ParseFilter(r.URL.Query().Get("filter"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var after *db.TaskCursor
+	if cursor := r.FormValue("cursor"); cursor != "" {
+		after, err = decodeCursor(cursor)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	page, err := a.store.TasksPage(limit, db.TaskQuery{Search: search, Filter: filter, Now: now}, after)
 	if err != nil {
 		writeJSONError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, TasksResp{
-		Tasks: tasks,
-	})
+	lang := requestLang(r)
+	for _, task := range page.Tasks {
+		fillRepeat(task, lang)
+	}
+
+	resp := TasksResp{
+		Tasks: page.Tasks,
+		Total: page.Total,
+	}
+	if page.Next != nil {
+		resp.NextCursor = encodeCursor(page.Next)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// encodeCursor записывает курсор непрозрачной для клиента строкой
+func encodeCursor(cursor *db.TaskCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (*db.TaskCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("incorrect cursor")
+	}
+
+	var cursor db.TaskCursor
+	if err = json.Unmarshal(data, &cursor); err != nil || cursor.Offset < 0 {
+		return nil, errors.New("incorrect cursor")
+	}
+	return &cursor, nil
 }
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// requestLocation возвращает часовой пояс пользователя. Он берётся из параметра tz,
+// заголовка X-Timezone или куки tz, а если ни один не задан - часовой пояс экземпляра API
+func (a *API) requestLocation(r *http.Request) (*time.Location, error) {
+	name := r.FormValue("tz")
+	if name == "" {
+		name = r.Header.Get("X-Timezone")
+	}
+	if name == "" {
+		if cookie, err := r.Cookie("tz"); err == nil {
+			name = cookie.Value
+		}
+	}
+	if name == "" {
+		return a.location, nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, errors.New("incorrect timezone")
+	}
+
+	return loc, nil
+}
+
+// requestNow возвращает текущий момент в часовом поясе пользователя
+func (a *API) requestNow(r *http.Request) (time.Time, error) {
+	loc, err := a.requestLocation(r)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return a.now().In(loc), nil
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+)
+
+// trashHandler работает с корзиной: GET - задачи в корзине, последние удалённые первыми,
+// DELETE - окончательно удалить задачу по id
+func (a *API) trashHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		// задачи с истёкшим сроком хранения не показываются, даже если очистка ещё не запускалась
+		if _, err := a.PurgeTrash(); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		tasks, err := a.store.Trash()
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		lang := requestLang(r)
+		for _, task := range tasks {
+			fillRepeat(task, lang)
+		}
+		writeJSON(w, http.StatusOK, TasksResp{Tasks: tasks, Total: len(tasks)})
+	case http.MethodDelete:
+		id := r.FormValue("id")
+		if id == "" {
+			writeJSONError(w, http.StatusBadRequest, errors.New("id is required"))
+			return
+		}
+
+		if err := a.store.PurgeTask(id); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{})
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+// restoreTaskHandler возвращает задачу из корзины
+func (a *API) restoreTaskHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	id := r.FormValue("id")
+	if id == "" {
+		writeJSONError(w, http.StatusBadRequest, errors.New("id is required"))
+		return
+	}
+
+	if err := a.store.RestoreTask(id); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+// PurgeTrash окончательно удаляет задачи, которые пролежали в корзине дольше срока хранения,
+// и возвращает их количество. Без срока хранения ничего не удаляется
+func (a *API) PurgeTrash() (int64, error) {
+	if a.trashDays <= 0 {
+		return 0, nil
+	}
+	return a.store.PurgeTrash(a.now().AddDate(0, 0, -a.trashDays))
+}
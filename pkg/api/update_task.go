@@ -1,22 +1,60 @@
 package api
 
 import (
+	"database/sql"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"strings"
-
-	"github.com/ezfroze/go_final_project/pkg/db"
 )
 
-func updateTask(w http.ResponseWriter, r *http.Request) {
+func (a *API) updateTask(w http.ResponseWriter, r *http.Request) {
 	if r.Body == nil {
 		writeJSONError(w, http.StatusBadRequest, errors.New("empty request body"))
 		return
 	}
 
-	var task db.Task
-	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.ID == "" {
+		writeJSONError(w, http.StatusBadRequest, errors.New("task id is empty"))
+		return
+	}
+
+	task, err := a.store.GetTask(req.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeJSONError(w, http.StatusBadRequest, errors.New("incorrect id for updating task"))
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// поля, которых нет в запросе, остаются как были: веб-интерфейс присылает только
+	// id, date, title, comment и repeat
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, ok := fields["repeat"]; !ok && fields["repeat_rule"] != nil {
+		// правило задано только структурой и заменяет прежнее
+		task.Repeat = ""
+	}
+	if err := json.Unmarshal(body, task); err != nil {
 		writeJSONError(w, http.StatusBadRequest, err)
 		return
 	}
@@ -28,14 +66,24 @@ func updateTask(w http.ResponseWriter, r *http.Request) {
 	}
 
 	task.Date = strings.TrimSpace(task.Date)
+	task.Time = strings.TrimSpace(task.Time)
 	task.Repeat = strings.TrimSpace(task.Repeat)
+	task.RepeatUntil = strings.TrimSpace(task.RepeatUntil)
+	task.RepeatFrom = strings.TrimSpace(task.RepeatFrom)
+	task.RepeatAnchor = strings.TrimSpace(task.RepeatAnchor)
+
+	now, err := a.requestNow(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
 
-	if err := checkDate(&task); err != nil {
+	if err := a.checkDate(task, now); err != nil {
 		writeJSONError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	err := db.UpdateTask(&task)
+	err = a.store.UpdateTask(task)
 	if err != nil {
 		writeJSONError(w, http.StatusInternalServerError, err)
 		return
@@ -0,0 +1,129 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Completion - запись о выполнении задачи. Записи остаются и после окончательного удаления задачи
+type Completion struct {
+	ID     string `json:"id"`
+	TaskID string `json:"task_id"`
+	// Title - заголовок задачи в момент выполнения
+	Title string `json:"title"`
+	// Date и Time - дата и время выполненного повторения по расписанию
+	Date string `json:"date"`
+	Time string `json:"time,omitempty"`
+	// DoneDate - день выполнения YYYYMMDD в часовом поясе пользователя
+	DoneDate string `json:"done_date"`
+	// CompletedAt - момент выполнения в UTC, RFC3339
+	CompletedAt string `json:"completed_at"`
+	Note        string `json:"note,omitempty"`
+	// NextDate - дата, на которую перенесена задача. Пусто - повторений больше нет
+	NextDate string `json:"next_date,omitempty"`
+}
+
+func (s *SQLiteStore) AddCompletion(c *Completion) (int64, error) {
+	return addCompletion(s.db, c)
+}
+
+// CompleteTask записывает выполнение и переносит задачу в одной транзакции
+func (s *SQLiteStore) CompleteTask(c *Completion, nextTime string, now time.Time) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var res sql.Result
+	if c.NextDate == "" {
+		res, err = tx.Exec(`UPDATE scheduler SET deleted_at = ? WHERE id = ? AND deleted_at = ''`,
+			trashTime(now), c.TaskID)
+	} else {
+		res, err = tx.Exec(`UPDATE scheduler SET date = ?, time = ?, repeat_count = max(repeat_count - 1, 0)
+			WHERE id = ? AND deleted_at = ''`, c.NextDate, nextTime, c.TaskID)
+	}
+	if err != nil {
+		return 0, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return 0, fmt.Errorf(`incorrect id for completing task`)
+	}
+
+	id, err := addCompletion(tx, c)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// execer - общее у *sql.DB и *sql.Tx
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func addCompletion(e execer, c *Completion) (int64, error) {
+	if c.TaskID == "" {
+		return 0, errors.New("task id is empty")
+	}
+
+	res, err := e.Exec(`INSERT INTO scheduler_completions (task_id, title, date, time, done_date, completed_at,
+		note, next_date) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.TaskID, c.Title, c.Date, c.Time, c.DoneDate, c.CompletedAt, c.Note, c.NextDate)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.LastInsertId()
+}
+
+// Completions возвращает выполнения задачи taskID с днём выполнения от from до to включительно,
+// последние первыми. Пустые taskID, from и to не ограничивают выборку
+func (s *SQLiteStore) Completions(taskID, from, to string) ([]Completion, error) {
+	var (
+		filter []string
+		args   []any
+	)
+	if taskID != "" {
+		filter = append(filter, `task_id = ?`)
+		args = append(args, taskID)
+	}
+	if from != "" {
+		filter = append(filter, `done_date >= ?`)
+		args = append(args, from)
+	}
+	if to != "" {
+		filter = append(filter, `done_date <= ?`)
+		args = append(args, to)
+	}
+
+	rows, err := s.db.Query(`SELECT id, task_id, title, date, time, done_date, completed_at, note, next_date
+		FROM scheduler_completions`+whereClause(filter)+` ORDER BY completed_at DESC, id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	completions := make([]Completion, 0)
+	for rows.Next() {
+		var (
+			c          Completion
+			id, taskID int64
+		)
+		err = rows.Scan(&id, &taskID, &c.Title, &c.Date, &c.Time, &c.DoneDate, &c.CompletedAt, &c.Note, &c.NextDate)
+		if err != nil {
+			return nil, err
+		}
+		c.ID = strconv.FormatInt(id, 10)
+		c.TaskID = strconv.FormatInt(taskID, 10)
+		completions = append(completions, c)
+	}
+	return completions, rows.Err()
+}
@@ -2,49 +2,21 @@ package db
 
 import (
 	"database/sql"
-	"os"
 
 	_ "modernc.org/sqlite"
 )
 
-const schema = `
-CREATE TABLE IF NOT EXISTS scheduler (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    date CHAR(8) NOT NULL DEFAULT '',
-    title TEXT NOT NULL DEFAULT '',
-    comment TEXT NOT NULL DEFAULT '',
-    repeat CHAR(128) NOT NULL DEFAULT ''
-);
-`
-
-const indexSchema = `
-CREATE INDEX IF NOT EXISTS idx_scheduler_date ON scheduler(date);
-`
-
-var db *sql.DB
-
+// Init открывает базу и применяет к ней миграции, которых в ней ещё нет.
+// Хранилище поверх базы создаёт NewSQLiteStore
 func Init(dbFile string) (*sql.DB, error) {
-	_, err := os.Stat(dbFile)
-
-	var install bool
-	if err != nil {
-		install = true
-	}
-
-	db, err = sql.Open("sqlite", dbFile)
+	db, err := sql.Open("sqlite", dbFile)
 	if err != nil {
 		return nil, err
 	}
 
-	if install {
-		_, err = db.Exec(schema)
-		if err != nil {
-			return nil, err
-		}
-		_, err = db.Exec(indexSchema)
-		if err != nil {
-			return nil, err
-		}
+	if err = Migrate(db); err != nil {
+		db.Close()
+		return nil, err
 	}
 
 	return db, nil
@@ -0,0 +1,53 @@
+package db
+
+import "errors"
+
+// Exceptions возвращает даты, в которые повторяющаяся задача пропускается
+func (s *SQLiteStore) Exceptions(taskID string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT date FROM scheduler_exceptions WHERE task_id = ? ORDER BY date ASC`, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dates := make([]string, 0)
+	for rows.Next() {
+		var date string
+		if err = rows.Scan(&date); err != nil {
+			return nil, err
+		}
+		dates = append(dates, date)
+	}
+
+	return dates, rows.Err()
+}
+
+func (s *SQLiteStore) AddException(taskID, date string) error {
+	if taskID == "" {
+		return errors.New("task id is empty")
+	}
+
+	if date == "" {
+		return errors.New("exception date is empty")
+	}
+
+	_, err := s.db.Exec(`INSERT OR IGNORE INTO scheduler_exceptions (task_id, date) VALUES (?, ?)`, taskID, date)
+	return err
+}
+
+func (s *SQLiteStore) DeleteException(taskID, date string) error {
+	res, err := s.db.Exec(`DELETE FROM scheduler_exceptions WHERE task_id = ? AND date = ?`, taskID, date)
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return errors.New("exception not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,483 @@
+package db
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Filter - разобранное выражение фильтра задач. Выражение состоит из условий:
+//
+//	date>=20250101 date<01.02.2025 date:today - дата задачи: YYYYMMDD, DD.MM.YYYY или today, завтра и т.п.
+//	repeat:yes repeat:no                      - задача повторяется или нет
+//	overdue:yes                               - дата или время задачи уже прошли
+//	text:счёт text:"счёт на оплату"           - слова в заголовке или комментарии, как в поиске
+//	title:счёт comment:"до 25"                - слова только в заголовке или только в комментарии
+//	счёт "счёт на оплату"                     - то же, что text:
+//
+// Условия подряд соединяются через AND, ещё есть OR, NOT, -условие и скобки:
+// date>=20250101 (repeat:yes OR overdue:yes) -title:отчёт
+// Других полей нет, например тегов у задач нет и tag:work - ошибка
+type Filter struct {
+	root filterNode
+}
+
+// FilterError - ошибка в выражении фильтра. Pos - номер символа, с 1
+type FilterError struct {
+	Pos int
+	Msg string
+}
+
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("filter: %s at position %d", e.Msg, e.Pos)
+}
+
+// filterNode - узел выражения фильтра. Выражение проверяется в памяти через match
+// и переводится в условие SQL с параметрами через sql
+type filterNode interface {
+	match(t *Task, doc searchDoc, now time.Time) bool
+	sql(now time.Time) (string, []any)
+}
+
+type (
+	filterAnd []filterNode
+	filterOr  []filterNode
+	filterNot struct{ node filterNode }
+
+	// filterDate сравнивает дату задачи с датой YYYYMMDD или с днём relative относительно now
+	filterDate struct {
+		op       string
+		date     string
+		relative int
+		isRel    bool
+	}
+	filterRepeat  bool
+	filterOverdue bool
+
+	// filterText ищет фразу во всех колонках (column = -1) или в одной колонке FTS5
+	filterText struct {
+		column int
+		phrase searchPhrase
+	}
+)
+
+// filterFields - поля условий и операторы, которые они поддерживают
+var filterFields = map[string][]string{
+	"date":    {":", "=", "!=", "<", "<=", ">", ">="},
+	"repeat":  {":", "="},
+	"overdue": {":", "="},
+	"text":    {":"},
+	"title":   {":"},
+	"comment": {":"},
+}
+
+// ftsColumns - колонки таблицы scheduler_fts по номерам, как в searchDoc
+var ftsColumns = []string{"title", "comment"}
+
+// Ограничения выражения фильтра: длина в символах и вложенность скобок и NOT.
+// Без них глубокая вложенность переполняет стек разбора
+const (
+	filterMaxLength = 1000
+	filterMaxDepth  = 64
+)
+
+var filterTermRe = regexp.MustCompile(`^([a-z]+)(>=|<=|!=|:|=|<|>)(.*)$`)
+
+// ParseFilter разбирает выражение фильтра. Пустое выражение - фильтр без условий, nil
+func ParseFilter(s string) (*Filter, error) {
+	runes := []rune(s)
+	if len(runes) > filterMaxLength {
+		return nil, &FilterError{Pos: filterMaxLength + 1, Msg: fmt.Sprintf("expression longer than %d characters", filterMaxLength)}
+	}
+
+	p := &filterParser{}
+	if err := p.lex(runes); err != nil {
+		return nil, err
+	}
+	if len(p.tokens) == 0 {
+		return nil, nil
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, p.errorf(p.tokens[p.pos], "unexpected %s", p.tokens[p.pos].text)
+	}
+	return &Filter{root: root}, nil
+}
+
+// filterToken - слово, скобка или оператор выражения. pos - номер первого символа, с 1
+type filterToken struct {
+	text string
+	pos  int
+}
+
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+	// depth - вложенность текущего условия в скобки и отрицания
+	depth int
+}
+
+// lex делит выражение на скобки и слова. Текст в кавычках - часть слова вместе с пробелами
+func (p *filterParser) lex(s []rune) error {
+	for i := 0; i < len(s); {
+		switch {
+		case unicode.IsSpace(s[i]):
+			i++
+		case s[i] == '(' || s[i] == ')':
+			p.tokens = append(p.tokens, filterToken{string(s[i]), i + 1})
+			i++
+		default:
+			start := i
+			for i < len(s) && !unicode.IsSpace(s[i]) && s[i] != '(' && s[i] != ')' {
+				if s[i] == '"' {
+					end := i + 1
+					for end < len(s) && s[end] != '"' {
+						end++
+					}
+					if end == len(s) {
+						return &FilterError{Pos: i + 1, Msg: "unterminated quote"}
+					}
+					i = end
+				}
+				i++
+			}
+			p.tokens = append(p.tokens, filterToken{string(s[start:i]), start + 1})
+		}
+	}
+	return nil
+}
+
+func (p *filterParser) errorf(tok filterToken, format string, args ...any) error {
+	return &FilterError{Pos: tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *filterParser) peek() (filterToken, bool) {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos], true
+	}
+	return filterToken{}, false
+}
+
+// end - позиция сразу после выражения, для ошибок о недостающей части
+func (p *filterParser) end() filterToken {
+	last := p.tokens[len(p.tokens)-1]
+	return filterToken{pos: last.pos + len([]rune(last.text))}
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	node, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	or := filterOr{node}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.text != "OR" {
+			break
+		}
+		p.pos++
+		if node, err = p.parseAnd(); err != nil {
+			return nil, err
+		}
+		or = append(or, node)
+	}
+
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	var and filterAnd
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.text == "OR" || tok.text == ")" {
+			break
+		}
+		if tok.text == "AND" {
+			if len(and) == 0 {
+				return nil, p.errorf(tok, "unexpected AND")
+			}
+			p.pos++
+		}
+
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, node)
+	}
+
+	switch len(and) {
+	case 0:
+		tok, ok := p.peek()
+		if !ok {
+			tok = p.end()
+			return nil, p.errorf(tok, "missing condition")
+		}
+		return nil, p.errorf(tok, "unexpected %s", tok.text)
+	case 1:
+		return and[0], nil
+	}
+	return and, nil
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	tok, ok := p.peek()
+	if !ok || tok.text == "OR" || tok.text == "AND" || tok.text == ")" {
+		if !ok {
+			tok = p.end()
+			return nil, p.errorf(tok, "missing condition")
+		}
+		return nil, p.errorf(tok, "unexpected %s", tok.text)
+	}
+
+	if tok.text == "NOT" || tok.text == "(" {
+		p.depth++
+		defer func() { p.depth-- }()
+		if p.depth > filterMaxDepth {
+			return nil, p.errorf(tok, "nesting deeper than %d", filterMaxDepth)
+		}
+	}
+
+	if tok.text == "NOT" {
+		p.pos++
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return filterNot{node}, nil
+	}
+
+	if tok.text == "(" {
+		p.pos++
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		closing, ok := p.peek()
+		if !ok || closing.text != ")" {
+			return nil, p.errorf(tok, "missing )")
+		}
+		p.pos++
+		return node, nil
+	}
+
+	p.pos++
+	if strings.HasPrefix(tok.text, "-") && len(tok.text) > 1 {
+		node, err := p.parseTerm(filterToken{tok.text[1:], tok.pos + 1})
+		if err != nil {
+			return nil, err
+		}
+		return filterNot{node}, nil
+	}
+	return p.parseTerm(tok)
+}
+
+// parseTerm разбирает условие поле:значение или слова для поиска
+func (p *filterParser) parseTerm(tok filterToken) (filterNode, error) {
+	m := filterTermRe.FindStringSubmatch(tok.text)
+	if m == nil {
+		return p.textTerm(tok, -1, tok.text)
+	}
+
+	field, op, value := m[1], m[2], m[3]
+	ops, ok := filterFields[field]
+	if !ok {
+		return nil, p.errorf(tok, "unknown field %s, supported fields: %s",
+			field, strings.Join(slices.Sorted(maps.Keys(filterFields)), ", "))
+	}
+	if !slices.Contains(ops, op) {
+		return nil, p.errorf(tok, "field %s does not support %s", field, op)
+	}
+	if value == "" {
+		return nil, p.errorf(tok, "missing value for %s", field)
+	}
+
+	switch field {
+	case "date":
+		return p.dateTerm(tok, op, value)
+	case "repeat", "overdue":
+		var yes bool
+		switch value {
+		case "yes", "true", "да":
+			yes = true
+		case "no", "false", "нет":
+		default:
+			return nil, p.errorf(tok, "%s expects yes or no", field)
+		}
+		if field == "repeat" {
+			return filterRepeat(yes), nil
+		}
+		return filterOverdue(yes), nil
+	case "title":
+		return p.textTerm(tok, 0, value)
+	case "comment":
+		return p.textTerm(tok, 1, value)
+	}
+	return p.textTerm(tok, -1, value)
+}
+
+func (p *filterParser) dateTerm(tok filterToken, op, value string) (filterNode, error) {
+	if op == ":" {
+		op = "="
+	}
+
+	if days, ok := relativeDates[strings.ToLower(value)]; ok {
+		return filterDate{op: op, relative: days, isRel: true}, nil
+	}
+	if date, err := time.Parse("20060102", value); err == nil {
+		return filterDate{op: op, date: date.Format("20060102")}, nil
+	}
+	if date, err := time.Parse("02.01.2006", value); err == nil {
+		return filterDate{op: op, date: date.Format("20060102")}, nil
+	}
+	return nil, p.errorf(tok, "invalid date %s", value)
+}
+
+// textTerm - слова для поиска. Текст в кавычках - точная фраза, иначе последнее слово ищется по началу
+func (p *filterParser) textTerm(tok filterToken, column int, value string) (filterNode, error) {
+	quoted := len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`)
+	if quoted {
+		value = value[1 : len(value)-1]
+	}
+	if strings.Contains(value, `"`) {
+		return nil, p.errorf(tok, "unexpected quote in %s", tok.text)
+	}
+
+	tokens := searchTokens(value)
+	if len(tokens) == 0 {
+		return nil, p.errorf(tok, "no words to search in %s", tok.text)
+	}
+	return filterText{column: column, phrase: searchPhrase{tokens: tokens, prefix: !quoted}}, nil
+}
+
+// where возвращает условие SQL с параметрами для задач из таблицы s
+func (f *Filter) where(now time.Time) (string, []any) {
+	return f.root.sql(now)
+}
+
+func (f *Filter) match(t *Task, now time.Time) bool {
+	return f.root.match(t, newSearchDoc(t), now)
+}
+
+func joinSQL(nodes []filterNode, sep string, now time.Time) (string, []any) {
+	parts := make([]string, 0, len(nodes))
+	var args []any
+	for _, n := range nodes {
+		part, partArgs := n.sql(now)
+		parts = append(parts, part)
+		args = append(args, partArgs...)
+	}
+	return "(" + strings.Join(parts, sep) + ")", args
+}
+
+func (f filterAnd) sql(now time.Time) (string, []any) { return joinSQL(f, " AND ", now) }
+func (f filterOr) sql(now time.Time) (string, []any)  { return joinSQL(f, " OR ", now) }
+
+func (f filterNot) sql(now time.Time) (string, []any) {
+	query, args := f.node.sql(now)
+	return "NOT " + query, args
+}
+
+func (f filterAnd) match(t *Task, doc searchDoc, now time.Time) bool {
+	for _, n := range f {
+		if !n.match(t, doc, now) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f filterOr) match(t *Task, doc searchDoc, now time.Time) bool {
+	for _, n := range f {
+		if n.match(t, doc, now) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f filterNot) match(t *Task, doc searchDoc, now time.Time) bool {
+	return !f.node.match(t, doc, now)
+}
+
+func (f filterDate) value(now time.Time) string {
+	if f.isRel {
+		return now.AddDate(0, 0, f.relative).Format("20060102")
+	}
+	return f.date
+}
+
+func (f filterDate) sql(now time.Time) (string, []any) {
+	return "(s.date " + f.op + " ?)", []any{f.value(now)}
+}
+
+func (f filterDate) match(t *Task, _ searchDoc, now time.Time) bool {
+	date := f.value(now)
+	switch f.op {
+	case "!=":
+		return t.Date != date
+	case "<":
+		return t.Date < date
+	case "<=":
+		return t.Date <= date
+	case ">":
+		return t.Date > date
+	case ">=":
+		return t.Date >= date
+	}
+	return t.Date == date
+}
+
+func (f filterRepeat) sql(time.Time) (string, []any) {
+	if f {
+		return "(s.repeat != '')", nil
+	}
+	return "(s.repeat = '')", nil
+}
+
+func (f filterRepeat) match(t *Task, _ searchDoc, _ time.Time) bool {
+	return bool(f) == (t.Repeat != "")
+}
+
+// Задача просрочена, если её день прошёл или сегодня уже прошло её время
+func (f filterOverdue) sql(now time.Time) (string, []any) {
+	today, clock := now.Format("20060102"), now.Format("15:04")
+	query := "(s.date < ? OR s.date = ? AND s.time != '' AND s.time < ?)"
+	if !f {
+		query = "NOT " + query
+	}
+	return query, []any{today, today, clock}
+}
+
+func (f filterOverdue) match(t *Task, _ searchDoc, now time.Time) bool {
+	today, clock := now.Format("20060102"), now.Format("15:04")
+	overdue := t.Date < today || t.Date == today && t.Time != "" && t.Time < clock
+	return bool(f) == overdue
+}
+
+func (f filterText) sql(time.Time) (string, []any) {
+	query := ftsQuery([]searchPhrase{f.phrase})
+	if f.column >= 0 {
+		query = ftsColumns[f.column] + " : " + query
+	}
+	return "(s.id IN (SELECT rowid FROM scheduler_fts WHERE scheduler_fts MATCH ?))", []any{query}
+}
+
+func (f filterText) match(_ *Task, doc searchDoc, _ time.Time) bool {
+	if f.column >= 0 {
+		return len(phraseHits(doc.tokens[f.column], f.phrase)) > 0
+	}
+	return doc.has(f.phrase)
+}
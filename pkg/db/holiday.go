@@ -0,0 +1,68 @@
+package db
+
+import "errors"
+
+// Holiday - нерабочий день производственного календаря
+type Holiday struct {
+	Date  string `json:"date"`
+	Title string `json:"title"`
+}
+
+// Holidays возвращает праздники по возрастанию даты
+func (s *SQLiteStore) Holidays() ([]Holiday, error) {
+	rows, err := s.db.Query(`SELECT date, title FROM scheduler_holidays ORDER BY date ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holidays := make([]Holiday, 0)
+	for rows.Next() {
+		var h Holiday
+		if err = rows.Scan(&h.Date, &h.Title); err != nil {
+			return nil, err
+		}
+		holidays = append(holidays, h)
+	}
+
+	return holidays, rows.Err()
+}
+
+// AddHolidays добавляет праздники одной транзакцией. Название уже существующего праздника заменяется
+func (s *SQLiteStore) AddHolidays(holidays []Holiday) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, h := range holidays {
+		if h.Date == "" {
+			return errors.New("holiday date is empty")
+		}
+
+		_, err = tx.Exec(`INSERT OR REPLACE INTO scheduler_holidays (date, title) VALUES (?, ?)`, h.Date, h.Title)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) DeleteHoliday(date string) error {
+	res, err := s.db.Exec(`DELETE FROM scheduler_holidays WHERE date = ?`, date)
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return errors.New("holiday not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,473 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore хранит задачи, даты-исключения, праздники и историю выполнений в памяти процесса.
+// Ведёт себя так же, как SQLiteStore: id не переиспользуются, поиск по словам
+// находит и сортирует задачи так же, как FTS5
+type MemoryStore struct {
+	mu     sync.RWMutex
+	tasks  map[int64]Task
+	lastID int64
+	// exceptions - даты-исключения по id задачи
+	exceptions map[string]map[string]bool
+	holidays   map[string]string
+	// completions - история выполнений в порядке записи
+	completions      []Completion
+	lastCompletionID int64
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		tasks:      make(map[int64]Task),
+		exceptions: make(map[string]map[string]bool),
+		holidays:   make(map[string]string),
+	}
+}
+
+// stored - копия задачи в том виде, в котором она хранится в базе
+func stored(task *Task) Task {
+	t := *task
+	t.RepeatDescription = ""
+	t.RepeatRule = nil
+	t.Highlight = nil
+	return t
+}
+
+// memoryID переводит id в число так же, как SQLite сравнивает строку с колонкой INTEGER
+func memoryID(id string) (int64, bool) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	return n, err == nil
+}
+
+func (s *MemoryStore) AddTask(task *Task) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	t := stored(task)
+	t.ID = strconv.FormatInt(s.lastID, 10)
+	t.DeletedAt = ""
+	s.tasks[s.lastID] = t
+
+	return s.lastID, nil
+}
+
+func (s *MemoryStore) Tasks(limit int, search string, now time.Time) ([]*Task, error) {
+	page, err := s.TasksPage(limit, TaskQuery{Search: search, Now: now}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return page.Tasks, nil
+}
+
+func (s *MemoryStore) TasksPage(limit int, q TaskQuery, after *TaskCursor) (*TaskPage, error) {
+	if limit < 1 {
+		return nil, errors.New("invalid limit")
+	}
+
+	search := q.Search
+	date, isDate := searchDate(search, q.Now)
+	all := strings.TrimSpace(search) == ""
+
+	var phrases []searchPhrase
+	if !isDate && !all {
+		phrases = parseSearch(search)
+		if len(phrases) == 0 {
+			return &TaskPage{Tasks: []*Task{}}, nil
+		}
+	}
+
+	s.mu.RLock()
+	tasks := make([]*Task, 0)
+	var docs, found []searchDoc
+	for _, t := range s.tasks {
+		// удалённые и отфильтрованные задачи всё равно входят в docs: idf в bm25 считается по всей таблице
+		keep := t.DeletedAt == "" && (q.Filter == nil || q.Filter.match(&t, q.Now))
+		switch {
+		case isDate:
+			if keep && t.Date == date {
+				tasks = append(tasks, &t)
+			}
+		case all:
+			if keep {
+				tasks = append(tasks, &t)
+			}
+		default:
+			doc := newSearchDoc(&t)
+			docs = append(docs, doc)
+			if keep && !slices.ContainsFunc(phrases, func(p searchPhrase) bool { return !doc.has(p) }) {
+				tasks = append(tasks, &t)
+				found = append(found, doc)
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	// при поиске по словам found[i] - документ задачи tasks[i]
+	rank := make(map[*Task]float64, len(found))
+	for i, t := range tasks[:len(found)] {
+		rank[t] = bm25(found[i], docs, phrases)
+		t.Highlight = newHighlight(highlight(found[i], 0, phrases), highlight(found[i], 1, phrases))
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		if rank[a] != rank[b] {
+			return rank[a] < rank[b]
+		}
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		if a.Time != b.Time {
+			return a.Time < b.Time
+		}
+		return taskNumber(a) < taskNumber(b)
+	})
+
+	total := len(tasks)
+	offset := 0
+	if after != nil {
+		if len(phrases) > 0 {
+			offset = max(0, min(after.Offset, total))
+			tasks = tasks[offset:]
+		} else {
+			// первая задача после курсора по дате, времени и id
+			i := sort.Search(len(tasks), func(i int) bool {
+				t := tasks[i]
+				if t.Date != after.Date {
+					return t.Date > after.Date
+				}
+				if t.Time != after.Time {
+					return t.Time > after.Time
+				}
+				return taskNumber(t) > after.ID
+			})
+			tasks = tasks[i:]
+		}
+	}
+
+	if len(tasks) > limit+1 {
+		tasks = tasks[:limit+1]
+	}
+	return newTaskPage(tasks, limit, offset, total), nil
+}
+
+func taskNumber(task *Task) int64 {
+	n, _ := memoryID(task.ID)
+	return n
+}
+
+func (s *MemoryStore) GetTask(id string) (*Task, error) {
+	n, ok := memoryID(id)
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.tasks[n]
+	if !ok || t.DeletedAt != "" {
+		return nil, sql.ErrNoRows
+	}
+	return &t, nil
+}
+
+func (s *MemoryStore) UpdateTask(task *Task) error {
+	if task.ID == "" {
+		return errors.New("task id is empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := memoryID(task.ID)
+	if !ok || !s.active(n) {
+		return fmt.Errorf(`incorrect id for updating task`)
+	}
+
+	t := stored(task)
+	t.ID = strconv.FormatInt(n, 10)
+	t.DeletedAt = ""
+	s.tasks[n] = t
+	return nil
+}
+
+// active сообщает, что задача с номером n есть и не в корзине
+func (s *MemoryStore) active(n int64) bool {
+	t, ok := s.tasks[n]
+	return ok && t.DeletedAt == ""
+}
+
+func (s *MemoryStore) DeleteTask(id string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := memoryID(id)
+	if !ok || !s.active(n) {
+		return fmt.Errorf(`incorrect id for deleting task`)
+	}
+
+	t := s.tasks[n]
+	t.DeletedAt = trashTime(now)
+	s.tasks[n] = t
+	return nil
+}
+
+func (s *MemoryStore) UpdateTaskDate(id, nextDate, nextTime string) error {
+	if id == "" {
+		return errors.New("task id is empty")
+	}
+
+	if nextDate == "" {
+		return errors.New("task next date is empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := memoryID(id)
+	if !ok || !s.active(n) {
+		return fmt.Errorf(`incorrect id for updating task`)
+	}
+
+	t := s.tasks[n]
+	t.Date, t.Time = nextDate, nextTime
+	s.tasks[n] = t
+	return nil
+}
+
+func (s *MemoryStore) Trash() ([]*Task, error) {
+	s.mu.RLock()
+	tasks := make([]*Task, 0)
+	for _, t := range s.tasks {
+		if t.DeletedAt != "" {
+			tasks = append(tasks, &t)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		if a.DeletedAt != b.DeletedAt {
+			return a.DeletedAt > b.DeletedAt
+		}
+		return taskNumber(a) > taskNumber(b)
+	})
+	return tasks, nil
+}
+
+func (s *MemoryStore) RestoreTask(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := memoryID(id)
+	t, exists := s.tasks[n]
+	if !ok || !exists || t.DeletedAt == "" {
+		return fmt.Errorf(`incorrect id for restoring task`)
+	}
+
+	t.DeletedAt = ""
+	s.tasks[n] = t
+	return nil
+}
+
+func (s *MemoryStore) PurgeTask(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := memoryID(id)
+	t, exists := s.tasks[n]
+	if !ok || !exists || t.DeletedAt == "" {
+		return fmt.Errorf(`incorrect id for purging task`)
+	}
+
+	delete(s.tasks, n)
+	delete(s.exceptions, strconv.FormatInt(n, 10))
+	return nil
+}
+
+func (s *MemoryStore) PurgeTrash(before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for n, t := range s.tasks {
+		if t.DeletedAt != "" && t.DeletedAt < trashTime(before) {
+			delete(s.tasks, n)
+			delete(s.exceptions, strconv.FormatInt(n, 10))
+			count++
+		}
+	}
+	return count, nil
+}
+
+// taskKey - ключ задачи в exceptions и completions: числовой id в каноническом виде, как его хранит SQLite
+func taskKey(taskID string) string {
+	if n, ok := memoryID(taskID); ok {
+		return strconv.FormatInt(n, 10)
+	}
+	return taskID
+}
+
+func (s *MemoryStore) Exceptions(taskID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	dates := make([]string, 0, len(s.exceptions[taskKey(taskID)]))
+	for date := range s.exceptions[taskKey(taskID)] {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+	return dates, nil
+}
+
+func (s *MemoryStore) AddException(taskID, date string) error {
+	if taskID == "" {
+		return errors.New("task id is empty")
+	}
+
+	if date == "" {
+		return errors.New("exception date is empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := taskKey(taskID)
+	if s.exceptions[key] == nil {
+		s.exceptions[key] = make(map[string]bool)
+	}
+	s.exceptions[key][date] = true
+	return nil
+}
+
+func (s *MemoryStore) DeleteException(taskID, date string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := taskKey(taskID)
+	if !s.exceptions[key][date] {
+		return errors.New("exception not found")
+	}
+	delete(s.exceptions[key], date)
+	return nil
+}
+
+func (s *MemoryStore) Holidays() ([]Holiday, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	holidays := make([]Holiday, 0, len(s.holidays))
+	for date, title := range s.holidays {
+		holidays = append(holidays, Holiday{Date: date, Title: title})
+	}
+	sort.Slice(holidays, func(i, j int) bool {
+		return holidays[i].Date < holidays[j].Date
+	})
+	return holidays, nil
+}
+
+func (s *MemoryStore) AddHolidays(holidays []Holiday) error {
+	// как и транзакция в SQLite, ошибка не оставляет добавленной части праздников
+	for _, h := range holidays {
+		if h.Date == "" {
+			return errors.New("holiday date is empty")
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, h := range holidays {
+		s.holidays[h.Date] = h.Title
+	}
+	return nil
+}
+
+func (s *MemoryStore) DeleteHoliday(date string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.holidays[date]; !ok {
+		return errors.New("holiday not found")
+	}
+	delete(s.holidays, date)
+	return nil
+}
+
+var (
+	_ Store = (*MemoryStore)(nil)
+	_ Store = (*SQLiteStore)(nil)
+)
+
+func (s *MemoryStore) AddCompletion(c *Completion) (int64, error) {
+	if c.TaskID == "" {
+		return 0, errors.New("task id is empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.addCompletion(c), nil
+}
+
+func (s *MemoryStore) CompleteTask(c *Completion, nextTime string, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := memoryID(c.TaskID)
+	if !ok || !s.active(n) {
+		return 0, fmt.Errorf(`incorrect id for completing task`)
+	}
+
+	t := s.tasks[n]
+	if c.NextDate == "" {
+		t.DeletedAt = trashTime(now)
+	} else {
+		t.Date, t.Time = c.NextDate, nextTime
+		t.RepeatCount = max(t.RepeatCount-1, 0)
+	}
+	s.tasks[n] = t
+	return s.addCompletion(c), nil
+}
+
+// addCompletion добавляет запись о выполнении, s.mu должен быть захвачен
+func (s *MemoryStore) addCompletion(c *Completion) int64 {
+	s.lastCompletionID++
+	rec := *c
+	rec.ID = strconv.FormatInt(s.lastCompletionID, 10)
+	rec.TaskID = taskKey(c.TaskID)
+	s.completions = append(s.completions, rec)
+	return s.lastCompletionID
+}
+
+func (s *MemoryStore) Completions(taskID, from, to string) ([]Completion, error) {
+	s.mu.RLock()
+	completions := make([]Completion, 0)
+	for _, c := range s.completions {
+		if taskID != "" && c.TaskID != taskKey(taskID) || from != "" && c.DoneDate < from || to != "" && c.DoneDate > to {
+			continue
+		}
+		completions = append(completions, c)
+	}
+	s.mu.RUnlock()
+
+	// записи добавлены по возрастанию id, поэтому при равном времени последние идут первыми
+	slices.Reverse(completions)
+	sort.SliceStable(completions, func(i, j int) bool {
+		return completions[i].CompletedAt > completions[j].CompletedAt
+	})
+	return completions, nil
+}
@@ -0,0 +1,274 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Migration - изменение схемы базы. Миграции применяются по порядку версий,
+// каждая в своей транзакции вместе с записью в schema_version
+type Migration struct {
+	Version int
+	Name    string
+	up      func(tx *sql.Tx) error
+}
+
+const versionSchema = `
+CREATE TABLE IF NOT EXISTS schema_version (
+    version INTEGER PRIMARY KEY,
+    name VARCHAR(256) NOT NULL DEFAULT '',
+    applied_at CHAR(20) NOT NULL DEFAULT ''
+);
+`
+
+// Исходная схема
+const schema = `
+CREATE TABLE IF NOT EXISTS scheduler (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    date CHAR(8) NOT NULL DEFAULT '',
+    title TEXT NOT NULL DEFAULT '',
+    comment TEXT NOT NULL DEFAULT '',
+    repeat CHAR(128) NOT NULL DEFAULT ''
+);
+`
+
+const indexSchema = `
+CREATE INDEX IF NOT EXISTS idx_scheduler_date ON scheduler(date);
+`
+
+const exceptionsSchema = `
+CREATE TABLE IF NOT EXISTS scheduler_exceptions (
+    task_id INTEGER NOT NULL,
+    date CHAR(8) NOT NULL,
+    PRIMARY KEY (task_id, date)
+);
+`
+
+const holidaysSchema = `
+CREATE TABLE IF NOT EXISTS scheduler_holidays (
+    date CHAR(8) PRIMARY KEY,
+    title VARCHAR(256) NOT NULL DEFAULT ''
+);
+`
+
+// Полнотекстовый индекс заголовков и комментариев. Индекс хранит только слова, текст
+// берётся из scheduler, триггеры обновляют индекс при любом изменении таблицы
+const ftsSchema = `
+CREATE VIRTUAL TABLE IF NOT EXISTS scheduler_fts USING fts5(
+    title, comment,
+    content = 'scheduler', content_rowid = 'id',
+    tokenize = 'unicode61 remove_diacritics 0', prefix = '2 3'
+);
+`
+
+const ftsInsertTrigger = `
+CREATE TRIGGER IF NOT EXISTS scheduler_fts_insert AFTER INSERT ON scheduler BEGIN
+    INSERT INTO scheduler_fts (rowid, title, comment) VALUES (new.id, new.title, new.comment);
+END;
+`
+
+const ftsDeleteTrigger = `
+CREATE TRIGGER IF NOT EXISTS scheduler_fts_delete AFTER DELETE ON scheduler BEGIN
+    INSERT INTO scheduler_fts (scheduler_fts, rowid, title, comment) VALUES ('delete', old.id, old.title, old.comment);
+END;
+`
+
+const ftsUpdateTrigger = `
+CREATE TRIGGER IF NOT EXISTS scheduler_fts_update AFTER UPDATE OF title, comment ON scheduler BEGIN
+    INSERT INTO scheduler_fts (scheduler_fts, rowid, title, comment) VALUES ('delete', old.id, old.title, old.comment);
+    INSERT INTO scheduler_fts (rowid, title, comment) VALUES (new.id, new.title, new.comment);
+END;
+`
+
+// История выполнений. task_id не ссылается на scheduler: история остаётся после удаления задачи
+const completionsSchema = `
+CREATE TABLE IF NOT EXISTS scheduler_completions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id INTEGER NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    date CHAR(8) NOT NULL DEFAULT '',
+    time CHAR(5) NOT NULL DEFAULT '',
+    done_date CHAR(8) NOT NULL DEFAULT '',
+    completed_at CHAR(20) NOT NULL DEFAULT '',
+    note TEXT NOT NULL DEFAULT '',
+    next_date CHAR(8) NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_completions_task ON scheduler_completions(task_id);
+CREATE INDEX IF NOT EXISTS idx_completions_done_date ON scheduler_completions(done_date);
+`
+
+// ftsRebuild заполняет индекс задачами, которые были в базе до миграции
+const ftsRebuild = `INSERT INTO scheduler_fts (scheduler_fts) VALUES ('rebuild');`
+
+// migrations - все миграции по возрастанию версий. Новую миграцию добавляют в конец списка,
+// уже выпущенные не меняют. Базы без schema_version считаются базами версии 0: таблицы
+// создаются через IF NOT EXISTS, а существующие колонки пропускаются, поэтому миграции
+// безопасно применяются и к базам, созданным до появления schema_version
+var migrations = []Migration{
+	{1, "initial schema", execAll(schema, indexSchema)},
+	{2, "repeat end conditions", addColumns("scheduler",
+		"repeat_until CHAR(8) NOT NULL DEFAULT ''",
+		"repeat_count INTEGER NOT NULL DEFAULT 0",
+	)},
+	{3, "repeat exceptions", execAll(exceptionsSchema)},
+	{4, "repeat from completion", addColumns("scheduler",
+		"repeat_from CHAR(8) NOT NULL DEFAULT ''",
+	)},
+	{5, "task time and duration", addColumns("scheduler",
+		"time CHAR(5) NOT NULL DEFAULT ''",
+		"duration INTEGER NOT NULL DEFAULT 0",
+	)},
+	{6, "repeat anchor and holidays", func(tx *sql.Tx) error {
+		if err := addColumns("scheduler", "repeat_anchor CHAR(8) NOT NULL DEFAULT ''")(tx); err != nil {
+			return err
+		}
+		return execAll(holidaysSchema)(tx)
+	}},
+	{7, "full-text search", execAll(ftsSchema, ftsInsertTrigger, ftsDeleteTrigger, ftsUpdateTrigger, ftsRebuild)},
+	{8, "soft delete", addColumns("scheduler",
+		"deleted_at CHAR(20) NOT NULL DEFAULT ''",
+	)},
+	{9, "completion history", execAll(completionsSchema)},
+}
+
+// Migrations возвращает все миграции по возрастанию версий
+func Migrations() []Migration {
+	return migrations
+}
+
+// SchemaVersion возвращает версию схемы базы: последнюю применённую миграцию или 0
+func SchemaVersion(database *sql.DB) (int, error) {
+	var exists int
+	err := database.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_version'`).Scan(&exists)
+	if err != nil || exists == 0 {
+		return 0, err
+	}
+
+	var version int
+	err = database.QueryRow(`SELECT coalesce(max(version), 0) FROM schema_version`).Scan(&version)
+	return version, err
+}
+
+// PendingMigrations возвращает миграции, которые ещё не применены к базе в файле dbFile.
+// Файл не создаётся: для несуществующей базы возвращаются все миграции
+func PendingMigrations(dbFile string) ([]Migration, error) {
+	if _, err := os.Stat(dbFile); err != nil {
+		return migrations, nil
+	}
+
+	database, err := sql.Open("sqlite", dbFile)
+	if err != nil {
+		return nil, err
+	}
+	defer database.Close()
+
+	version, err := SchemaVersion(database)
+	if err != nil {
+		return nil, err
+	}
+	return pending(version), nil
+}
+
+func pending(version int) []Migration {
+	for i, m := range migrations {
+		if m.Version > version {
+			return migrations[i:]
+		}
+	}
+	return nil
+}
+
+// Migrate применяет к базе миграции, которых в ней ещё нет. Ошибка в миграции
+// откатывает только её, ранее применённые миграции остаются
+func Migrate(database *sql.DB) error {
+	if _, err := database.Exec(versionSchema); err != nil {
+		return err
+	}
+
+	version, err := SchemaVersion(database)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range pending(version) {
+		if err := apply(database, m); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+func apply(database *sql.DB, m Migration) error {
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = m.up(tx); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)`,
+		m.Version, m.Name, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// execAll выполняет запросы миграции по очереди
+func execAll(queries ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, query := range queries {
+			if _, err := tx.Exec(query); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// addColumns добавляет в таблицу колонки, которых в ней ещё нет.
+// Каждая колонка задаётся как в ALTER TABLE ADD COLUMN: имя, тип и ограничения
+func addColumns(table string, columns ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		existing, err := tableColumns(tx, table)
+		if err != nil {
+			return err
+		}
+
+		for _, column := range columns {
+			name, _, _ := strings.Cut(column, " ")
+			if existing[name] {
+				continue
+			}
+			if _, err := tx.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s`, table, column)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func tableColumns(tx *sql.Tx, table string) (map[string]bool, error) {
+	rows, err := tx.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
@@ -0,0 +1,288 @@
+package db
+
+import (
+	"html"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Полнотекстовый поиск по заголовкам и комментариям. SQLiteStore ищет через таблицу FTS5
+// scheduler_fts, MemoryStore - по тем же правилам в памяти: слова разбиваются и приводятся
+// к нижнему регистру как в токенизаторе unicode61, результаты сортируются по bm25
+
+// searchWeights - веса заголовка и комментария в bm25, как в запросе SQLiteStore.Tasks
+var searchWeights = [2]float64{2, 1}
+
+// Метки начала и конца совпадения в тексте, который возвращает highlight
+const (
+	markStart = "\x02"
+	markEnd   = "\x03"
+)
+
+// snippetWords - сколько слов комментария остаётся в выдержке вокруг первого совпадения
+const snippetWords = 12
+
+// TaskHighlight - заголовок и выдержка из комментария задачи, найденной поиском.
+// Совпадения обёрнуты в <mark>, остальной текст экранирован для HTML
+type TaskHighlight struct {
+	Title string `json:"title"`
+	// Comment - слова комментария вокруг первого совпадения, пусто - в комментарии совпадений нет
+	Comment string `json:"comment,omitempty"`
+}
+
+// searchPhrase - фраза запроса: слова подряд. У фразы с prefix последнее слово ищется по началу
+type searchPhrase struct {
+	tokens []string
+	prefix bool
+}
+
+// parseSearch разбирает строку поиска. Слова в кавычках - точная фраза, остальные слова
+// ищутся по началу: пла находит Планы. Задача должна содержать все фразы запроса
+func parseSearch(search string) []searchPhrase {
+	var phrases []searchPhrase
+	for i, part := range strings.Split(search, `"`) {
+		if i%2 == 1 {
+			if tokens := searchTokens(part); len(tokens) > 0 {
+				phrases = append(phrases, searchPhrase{tokens: tokens})
+			}
+			continue
+		}
+
+		for _, word := range strings.Fields(part) {
+			if tokens := searchTokens(word); len(tokens) > 0 {
+				phrases = append(phrases, searchPhrase{tokens: tokens, prefix: true})
+			}
+		}
+	}
+	return phrases
+}
+
+// ftsQuery записывает фразы запросом FTS5: "слово слово"* AND "фраза"
+func ftsQuery(phrases []searchPhrase) string {
+	parts := make([]string, 0, len(phrases))
+	for _, p := range phrases {
+		part := `"` + strings.Join(p.tokens, " ") + `"`
+		if p.prefix {
+			part += "*"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// token - слово текста в нижнем регистре и его границы в байтах
+type token struct {
+	word       string
+	start, end int
+}
+
+// isTokenRune - символ слова, как в unicode61: буквы, цифры и символы частного использования
+func isTokenRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.Is(unicode.Co, r)
+}
+
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		switch {
+		case isTokenRune(r) && start < 0:
+			start = i
+		case !isTokenRune(r) && start >= 0:
+			tokens = append(tokens, token{strings.ToLower(text[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{strings.ToLower(text[start:]), start, len(text)})
+	}
+	return tokens
+}
+
+func searchTokens(text string) []string {
+	tokens := tokenize(text)
+	words := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		words = append(words, t.word)
+	}
+	return words
+}
+
+// phraseAt сообщает, что фраза начинается со слова tokens[i]
+func phraseAt(tokens []token, i int, p searchPhrase) bool {
+	if i+len(p.tokens) > len(tokens) {
+		return false
+	}
+	last := len(p.tokens) - 1
+	for k, word := range p.tokens {
+		if k == last && p.prefix {
+			return strings.HasPrefix(tokens[i+k].word, word)
+		}
+		if tokens[i+k].word != word {
+			return false
+		}
+	}
+	return true
+}
+
+// phraseHits возвращает номера слов, с которых начинается фраза
+func phraseHits(tokens []token, p searchPhrase) []int {
+	var hits []int
+	for i := range tokens {
+		if phraseAt(tokens, i, p) {
+			hits = append(hits, i)
+		}
+	}
+	return hits
+}
+
+// searchDoc - заголовок и комментарий задачи, разбитые на слова
+type searchDoc struct {
+	columns [2]string
+	tokens  [2][]token
+}
+
+func newSearchDoc(task *Task) searchDoc {
+	doc := searchDoc{columns: [2]string{task.Title, task.Comment}}
+	for c, text := range doc.columns {
+		doc.tokens[c] = tokenize(text)
+	}
+	return doc
+}
+
+func (d searchDoc) size() int {
+	return len(d.tokens[0]) + len(d.tokens[1])
+}
+
+func (d searchDoc) has(p searchPhrase) bool {
+	return len(phraseHits(d.tokens[0], p)) > 0 || len(phraseHits(d.tokens[1], p)) > 0
+}
+
+// bm25 считает оценку документа так же, как функция bm25 в FTS5: чем меньше, тем выше в выдаче.
+// docs - все документы таблицы, по ним считаются idf фраз и средняя длина документа
+func bm25(doc searchDoc, docs []searchDoc, phrases []searchPhrase) float64 {
+	const (
+		k1 = 1.2
+		b  = 0.75
+	)
+
+	total := 0
+	for _, d := range docs {
+		total += d.size()
+	}
+	avgdl := float64(total) / float64(len(docs))
+
+	score := 0.0
+	for _, p := range phrases {
+		hit := 0
+		for _, d := range docs {
+			if d.has(p) {
+				hit++
+			}
+		}
+		idf := math.Log((float64(len(docs)-hit) + 0.5) / (float64(hit) + 0.5))
+		if idf <= 0 {
+			idf = 1e-6
+		}
+
+		freq := 0.0
+		for c := range doc.tokens {
+			freq += float64(len(phraseHits(doc.tokens[c], p))) * searchWeights[c]
+		}
+		score += idf * ((freq * (k1 + 1.0)) / (freq + k1*(1-b+b*float64(doc.size())/avgdl)))
+	}
+	return -1 * score
+}
+
+// highlight отмечает совпадения фраз в колонке документа метками markStart и markEnd,
+// как функция highlight в FTS5: пересекающиеся совпадения объединяются
+func highlight(doc searchDoc, column int, phrases []searchPhrase) string {
+	tokens := doc.tokens[column]
+
+	type span struct{ first, last int }
+	var spans []span
+	for _, p := range phrases {
+		for _, i := range phraseHits(tokens, p) {
+			spans = append(spans, span{i, i + len(p.tokens) - 1})
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].first < spans[j].first })
+
+	text := doc.columns[column]
+	var sb strings.Builder
+	pos := 0
+	for i := 0; i < len(spans); {
+		first, last := spans[i].first, spans[i].last
+		for i++; i < len(spans) && spans[i].first <= last; i++ {
+			last = max(last, spans[i].last)
+		}
+		start, end := tokens[first].start, tokens[last].end
+		sb.WriteString(text[pos:start])
+		sb.WriteString(markStart + text[start:end] + markEnd)
+		pos = end
+	}
+	sb.WriteString(text[pos:])
+	return sb.String()
+}
+
+// newHighlight собирает TaskHighlight из заголовка и комментария с метками совпадений
+func newHighlight(title, comment string) *TaskHighlight {
+	return &TaskHighlight{
+		Title:   renderMarks(title),
+		Comment: renderMarks(snippet(comment)),
+	}
+}
+
+// snippet оставляет до snippetWords слов текста, начиная чуть раньше первого совпадения.
+// Без совпадений возвращает пустую строку
+func snippet(marked string) string {
+	words := strings.Fields(marked)
+	first := slices.IndexFunc(words, func(w string) bool {
+		return strings.Contains(w, markStart)
+	})
+	if first < 0 {
+		return ""
+	}
+
+	start := max(0, first-snippetWords/4)
+	end := min(len(words), start+snippetWords)
+	text := strings.Join(words[start:end], " ")
+	if start > 0 {
+		text = "…" + text
+	}
+	if end < len(words) {
+		text += "…"
+	}
+	return text
+}
+
+// renderMarks экранирует текст для HTML и заменяет метки совпадений на <mark>.
+// Совпадение, обрезанное концом выдержки, закрывается в конце текста
+func renderMarks(marked string) string {
+	var sb strings.Builder
+	open := false
+	for marked != "" {
+		i := strings.IndexAny(marked, markStart+markEnd)
+		if i < 0 {
+			sb.WriteString(html.EscapeString(marked))
+			break
+		}
+		sb.WriteString(html.EscapeString(marked[:i]))
+		switch {
+		case marked[i:i+1] == markStart && !open:
+			sb.WriteString("<mark>")
+			open = true
+		case marked[i:i+1] == markEnd && open:
+			sb.WriteString("</mark>")
+			open = false
+		}
+		marked = marked[i+1:]
+	}
+	if open {
+		sb.WriteString("</mark>")
+	}
+	return sb.String()
+}
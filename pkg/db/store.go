@@ -0,0 +1,112 @@
+package db
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+)
+
+// TaskStore - хранилище задач. Реализации: SQLiteStore и MemoryStore.
+// Поведение реализаций одинаково и проверяется общим набором тестов
+type TaskStore interface {
+	// AddTask добавляет задачу и возвращает её id
+	AddTask(task *Task) (int64, error)
+	// Tasks возвращает не больше limit задач по возрастанию даты, времени и id.
+	// search - дата DD.MM.YYYY, слово вроде "сегодня", которое считается от now, или слова
+	// заголовка и комментария. Найденные по словам задачи сортируются по bm25 и получают Highlight
+	Tasks(limit int, search string, now time.Time) ([]*Task, error)
+	// TasksPage работает как Tasks, но ещё отбирает задачи по фильтру, возвращает страницу после
+	// курсора after (nil - первую), курсор следующей страницы и количество всех найденных задач
+	TasksPage(limit int, q TaskQuery, after *TaskCursor) (*TaskPage, error)
+	// GetTask возвращает задачу по id или sql.ErrNoRows
+	GetTask(id string) (*Task, error)
+	UpdateTask(task *Task) error
+	// DeleteTask переносит задачу в корзину с временем удаления now. Задачи в корзине не видны
+	// остальным методам, кроме Trash, RestoreTask и PurgeTask
+	DeleteTask(id string, now time.Time) error
+	// UpdateTaskDate переносит задачу на новые дату и время
+	UpdateTaskDate(id, nextDate, nextTime string) error
+
+	// Trash возвращает задачи из корзины, последние удалённые первыми
+	Trash() ([]*Task, error)
+	RestoreTask(id string) error
+	// PurgeTask окончательно удаляет задачу из корзины
+	PurgeTask(id string) error
+	// PurgeTrash окончательно удаляет задачи, попавшие в корзину раньше before, и возвращает их количество
+	PurgeTrash(before time.Time) (int64, error)
+}
+
+// TaskQuery - условия отбора задач для TasksPage
+type TaskQuery struct {
+	// Search - строка поиска, как в Tasks
+	Search string
+	// Filter - выражение фильтра из ParseFilter, nil - без фильтра
+	Filter *Filter
+	// Now - текущий момент в часовом поясе пользователя, от него считаются today и overdue
+	Now time.Time
+}
+
+// TaskCursor - место в списке задач, с которого начинается следующая страница. Список по дате
+// продолжается после даты, времени и id последней задачи, поэтому новые и удалённые задачи не сдвигают
+// страницы. Результаты поиска по словам сортируются по bm25 и продолжаются после Offset задач
+type TaskCursor struct {
+	Date   string `json:"d,omitempty"`
+	Time   string `json:"t,omitempty"`
+	ID     int64  `json:"i,omitempty"`
+	Offset int    `json:"o,omitempty"`
+}
+
+// TaskPage - страница списка задач
+type TaskPage struct {
+	Tasks []*Task
+	// Next - курсор следующей страницы, nil - это последняя страница
+	Next *TaskCursor
+	// Total - количество всех задач, найденных по строке поиска
+	Total int
+}
+
+// newTaskPage собирает страницу из задач, выбранных с запасом в одну задачу сверх limit.
+// offset - сколько задач пропущено до страницы
+func newTaskPage(tasks []*Task, limit, offset, total int) *TaskPage {
+	page := &TaskPage{Tasks: tasks, Total: total}
+	if len(tasks) > limit {
+		page.Tasks = tasks[:limit]
+		last := page.Tasks[limit-1]
+		id, _ := strconv.ParseInt(last.ID, 10, 64)
+		page.Next = &TaskCursor{Date: last.Date, Time: last.Time, ID: id, Offset: offset + limit}
+	}
+	return page
+}
+
+// Store - всё хранилище планировщика: задачи, даты-исключения, праздники и история выполнений
+type Store interface {
+	TaskStore
+	// Exceptions возвращает даты, в которые повторяющаяся задача пропускается, по возрастанию
+	Exceptions(taskID string) ([]string, error)
+	AddException(taskID, date string) error
+	DeleteException(taskID, date string) error
+	// Holidays возвращает праздники по возрастанию даты
+	Holidays() ([]Holiday, error)
+	// AddHolidays добавляет праздники: все или ни одного. Название уже существующего праздника заменяется
+	AddHolidays(holidays []Holiday) error
+	DeleteHoliday(date string) error
+	// AddCompletion записывает выполнение задачи и возвращает id записи
+	AddCompletion(c *Completion) (int64, error)
+	// CompleteTask атомарно записывает выполнение c и переносит задачу c.TaskID на c.NextDate
+	// и время nextTime, уменьшая repeat_count, если он задан. Пустой c.NextDate - задача
+	// переносится в корзину с временем now. Если задачи нет, выполнение не записывается
+	CompleteTask(c *Completion, nextTime string, now time.Time) (int64, error)
+	// Completions возвращает выполнения задачи taskID с днём выполнения от from до to включительно,
+	// последние первыми. Пустые taskID, from и to не ограничивают выборку
+	Completions(taskID, from, to string) ([]Completion, error)
+}
+
+// SQLiteStore хранит задачи в таблице scheduler базы SQLite
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore возвращает хранилище поверх открытой базы с применёнными миграциями
+func NewSQLiteStore(database *sql.DB) *SQLiteStore {
+	return &SQLiteStore{db: database}
+}
@@ -5,20 +5,92 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type Task struct {
-	ID      string `json:"id"`
-	Date    string `json:"date"`
-	Title   string `json:"title"`
-	Comment string `json:"comment"`
-	Repeat  string `json:"repeat"`
+	ID   string `json:"id"`
+	Date string `json:"date"`
+	// Time - время задачи HH:MM, пусто - задача на весь день
+	Time string `json:"time,omitempty"`
+	// Duration - длительность в минутах
+	Duration int    `json:"duration,omitempty"`
+	Title    string `json:"title"`
+	Comment  string `json:"comment"`
+	Repeat   string `json:"repeat"`
+	// RepeatUntil - дата YYYYMMDD, после которой задача больше не повторяется
+	RepeatUntil string `json:"repeat_until,omitempty"`
+	// RepeatCount - сколько раз задача ещё повторится, включая текущий. 0 - без ограничений
+	RepeatCount int `json:"repeat_count,omitempty"`
+	// RepeatFrom - точка отсчёта повторений: пусто - дата задачи, done - день выполнения
+	RepeatFrom string `json:"repeat_from,omitempty"`
+	// RepeatAnchor - дата начала серии до переноса на рабочий день (правила с fwd и back).
+	// От неё считаются следующие повторения, чтобы перенос не сдвигал серию
+	RepeatAnchor string `json:"repeat_anchor,omitempty"`
+	// RepeatDescription - описание правила повторения для пользователя, в базе не хранится
+	RepeatDescription string `json:"repeat_description,omitempty"`
+	// RepeatRule - правило повторения в виде структуры, то же, что Repeat. В базе не хранится
+	RepeatRule *RepeatRule `json:"repeat_rule,omitempty"`
+	// Highlight - совпадения с поиском по словам, только в результатах такого поиска
+	Highlight *TaskHighlight `json:"highlight,omitempty"`
+	// DeletedAt - время переноса в корзину в UTC, RFC3339. Пусто - задача не удалена
+	DeletedAt string `json:"deleted_at,omitempty"`
 }
 
-func AddTask(task *Task) (int64, error) {
-	res, err := db.Exec(`INSERT INTO scheduler (date, title, comment, repeat) VALUES (?, ?, ?, ?)`,
-		task.Date, task.Title, task.Comment, task.Repeat)
+// RepeatRule - правило повторения в виде JSON. Переводится в строку repeat и обратно без потерь
+//
+//	{"type": "w", "interval": 2, "days": [1, 4]}                       - w 1,4 2
+//	{"type": "m", "interval": 1, "days": [1, -1], "months": [2, 8]}    - m 1,-1 2,8
+//	{"type": "m", "interval": 1, "ordinals": [{"n": 2, "weekday": 2}]} - m 2tu
+type RepeatRule struct {
+	// Type - тип правила: d, w, m, y, h или b
+	Type string `json:"type"`
+	// Interval - шаг правила: дни для d, недели для w, месяцы для m, годы для y, часы для h, рабочие дни для b
+	Interval int `json:"interval"`
+	// Days - дни недели 1..7 для w или дни месяца для m (отрицательные - от конца месяца)
+	Days []int `json:"days,omitempty"`
+	// Dates - дни года для y
+	Dates []RepeatYearDay `json:"dates,omitempty"`
+	// Clamp - дни месяца, которых нет в коротком месяце, переносятся на последний или первый день
+	Clamp bool `json:"clamp,omitempty"`
+	// Months - месяцы 1..12 для m
+	Months []int `json:"months,omitempty"`
+	// Ordinals - n-е дни недели месяца для m
+	Ordinals []RepeatOrdinal `json:"ordinals,omitempty"`
+	// SetPos - порядковые номера выбранных дат в месяце, как BYSETPOS в RRULE
+	SetPos []int `json:"set_pos,omitempty"`
+	// Roll - перенос нерабочего дня: fwd или back
+	Roll string `json:"roll,omitempty"`
+	// End - условие окончания, как COUNT и UNTIL в RRULE
+	End *RepeatEnd `json:"end,omitempty"`
+}
+
+// RepeatOrdinal - n-й день недели месяца: n = -1, weekday = 5 - последняя пятница.
+// n = 0 - каждый такой день недели
+type RepeatOrdinal struct {
+	N       int `json:"n"`
+	Weekday int `json:"weekday"`
+}
+
+// RepeatYearDay - день года в правиле y: {"day": 25, "month": 12}
+type RepeatYearDay struct {
+	Day   int `json:"day"`
+	Month int `json:"month"`
+}
+
+type RepeatEnd struct {
+	// Until - дата YYYYMMDD последнего повторения
+	Until string `json:"until,omitempty"`
+	// Count - количество повторений
+	Count int `json:"count,omitempty"`
+}
+
+func (s *SQLiteStore) AddTask(task *Task) (int64, error) {
+	res, err := s.db.Exec(`INSERT INTO scheduler (date, time, duration, title, comment, repeat,
+		repeat_until, repeat_count, repeat_from, repeat_anchor) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		task.Date, task.Time, task.Duration, task.Title, task.Comment, task.Repeat,
+		task.RepeatUntil, task.RepeatCount, task.RepeatFrom, task.RepeatAnchor)
 	if err != nil {
 		return 0, err
 	}
@@ -26,55 +98,124 @@ func AddTask(task *Task) (int64, error) {
 	return res.LastInsertId()
 }
 
-func Tasks(limit int, search string) ([]*Task, error) {
+// relativeDates - слова поиска, означающие день относительно сегодняшнего
+var relativeDates = map[string]int{
+	"вчера":     -1,
+	"сегодня":   0,
+	"завтра":    1,
+	"yesterday": -1,
+	"today":     0,
+	"tomorrow":  1,
+}
+
+// searchDate возвращает дату YYYYMMDD, если строка поиска - дата DD.MM.YYYY
+// или слово вроде "сегодня". Относительные даты считаются от now в его часовом поясе
+func searchDate(search string, now time.Time) (string, bool) {
+	if days, ok := relativeDates[strings.ToLower(strings.TrimSpace(search))]; ok {
+		return now.AddDate(0, 0, days).Format("20060102"), true
+	}
+
 	date, err := time.Parse("02.01.2006", search)
-	isDate := err == nil
-
-	var tasks []*Task
-
-	var query string
-	if isDate {
-		query = `SELECT id, date, title, comment, repeat
-                 FROM scheduler
-                 WHERE date = ?
-                 ORDER BY date ASC, id ASC
-                 LIMIT ?`
-	} else {
-		query = `SELECT id, date, title, comment, repeat
-                 FROM scheduler
-                 WHERE title LIKE ? OR comment LIKE ?
-                 ORDER BY date ASC, id ASC
-                 LIMIT ?`
-	}
-
-	stmt, err := db.Prepare(query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to prepare query: %v", err)
+		return "", false
 	}
-	defer stmt.Close()
+	return date.Format("20060102"), true
+}
 
-	var rows *sql.Rows
-	if isDate {
-		rows, err = stmt.Query(date.Format("20060102"), limit)
-	} else {
-		searchParam := "%" + search + "%"
-		rows, err = stmt.Query(searchParam, searchParam, limit)
+// Tasks возвращает не больше limit задач, отфильтрованных строкой поиска search.
+// now - текущий момент в часовом поясе пользователя
+func (s *SQLiteStore) Tasks(limit int, search string, now time.Time) ([]*Task, error) {
+	page, err := s.TasksPage(limit, TaskQuery{Search: search, Now: now}, nil)
+	if err != nil {
+		return nil, err
 	}
+	return page.Tasks, nil
+}
+
+// TasksPage возвращает страницу из не больше limit задач после курсора after
+func (s *SQLiteStore) TasksPage(limit int, q TaskQuery, after *TaskCursor) (*TaskPage, error) {
+	if limit < 1 {
+		return nil, errors.New("invalid limit")
+	}
+
+	search := q.Search
+	date, isDate := searchDate(search, q.Now)
+	fullText := !isDate && strings.TrimSpace(search) != ""
+
+	var (
+		from    = `scheduler s`
+		filter  = []string{`s.deleted_at = ''`}
+		args    []any
+		columns = `s.id, s.date, s.time, s.duration, s.title, s.comment, s.repeat, s.repeat_until, s.repeat_count, s.repeat_from, s.repeat_anchor`
+		order   = `s.date ASC, s.time ASC, s.id ASC`
+	)
+	switch {
+	case isDate:
+		filter = append(filter, `s.date = ?`)
+		args = append(args, date)
+	case fullText:
+		phrases := parseSearch(search)
+		if len(phrases) == 0 {
+			return &TaskPage{Tasks: []*Task{}}, nil
+		}
+		from = `scheduler_fts JOIN scheduler s ON s.id = scheduler_fts.rowid`
+		filter = append(filter, `scheduler_fts MATCH ?`)
+		args = append(args, ftsQuery(phrases))
+		// метки совпадений char(2) и char(3) - это markStart и markEnd, веса bm25 совпадают с searchWeights
+		columns += `, highlight(scheduler_fts, 0, char(2), char(3)), highlight(scheduler_fts, 1, char(2), char(3))`
+		order = `bm25(scheduler_fts, 2.0, 1.0), ` + order
+	}
+	if q.Filter != nil {
+		cond, condArgs := q.Filter.where(q.Now)
+		filter = append(filter, cond)
+		args = append(args, condArgs...)
+	}
+
+	var total int
+	err := s.db.QueryRow(`SELECT count(*) FROM `+from+whereClause(filter), args...).Scan(&total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tasks: %v", err)
+	}
+
+	// результаты поиска по словам листаются смещением: у порядка по bm25 нет ключа,
+	// остальные списки - по дате, времени и id последней задачи страницы
+	offset := 0
+	if after != nil {
+		if fullText {
+			offset = max(0, after.Offset)
+		} else {
+			filter = append(filter, `(s.date, s.time, s.id) > (?, ?, ?)`)
+			args = append(args, after.Date, after.Time, after.ID)
+		}
+	}
+
+	// лишняя задача показывает, что есть следующая страница
+	query := `SELECT ` + columns + ` FROM ` + from + whereClause(filter) + ` ORDER BY ` + order + ` LIMIT ? OFFSET ?`
+	rows, err := s.db.Query(query, append(args, limit+1, offset)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %v", err)
 	}
 	defer rows.Close()
 
+	tasks := make([]*Task, 0)
 	for rows.Next() {
 		var (
-			id int64
-			t  Task
+			id             int64
+			t              Task
+			title, comment string
 		)
-		err = rows.Scan(&id, &t.Date, &t.Title, &t.Comment, &t.Repeat)
-		if err != nil {
+		dest := []any{&id, &t.Date, &t.Time, &t.Duration, &t.Title, &t.Comment, &t.Repeat,
+			&t.RepeatUntil, &t.RepeatCount, &t.RepeatFrom, &t.RepeatAnchor}
+		if fullText {
+			dest = append(dest, &title, &comment)
+		}
+		if err = rows.Scan(dest...); err != nil {
 			return nil, err
 		}
 		t.ID = strconv.FormatInt(id, 10)
+		if fullText {
+			t.Highlight = newHighlight(title, comment)
+		}
 		tasks = append(tasks, &t)
 	}
 
@@ -82,18 +223,23 @@ func Tasks(limit int, search string) ([]*Task, error) {
 		return nil, err
 	}
 
-	if tasks == nil {
-		return []*Task{}, err
-	}
+	return newTaskPage(tasks, limit, offset, total), nil
+}
 
-	return tasks, nil
+func whereClause(filter []string) string {
+	if len(filter) == 0 {
+		return ""
+	}
+	return ` WHERE ` + strings.Join(filter, ` AND `)
 }
 
-func GetTask(id string) (*Task, error) {
+func (s *SQLiteStore) GetTask(id string) (*Task, error) {
 	var task Task
 
-	err := db.QueryRow("SELECT id, date, title, comment, repeat FROM scheduler WHERE id = ?", id).
-		Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat)
+	err := s.db.QueryRow(`SELECT id, date, time, duration, title, comment, repeat, repeat_until, repeat_count, repeat_from, repeat_anchor
+		FROM scheduler WHERE id = ? AND deleted_at = ''`, id).
+		Scan(&task.ID, &task.Date, &task.Time, &task.Duration, &task.Title, &task.Comment, &task.Repeat,
+			&task.RepeatUntil, &task.RepeatCount, &task.RepeatFrom, &task.RepeatAnchor)
 
 	if err != nil {
 		return nil, err
@@ -102,7 +248,7 @@ func GetTask(id string) (*Task, error) {
 	return &task, nil
 }
 
-func UpdateTask(task *Task) error {
+func (s *SQLiteStore) UpdateTask(task *Task) error {
 	if task.ID == "" {
 		return errors.New("task id is empty")
 	}
@@ -112,14 +258,26 @@ func UpdateTask(task *Task) error {
 		SET title = :title,
 		    comment = :comment,
 		    repeat = :repeat,
-		    date = :date
-		WHERE id = :id`
+		    repeat_until = :repeat_until,
+		    repeat_count = :repeat_count,
+		    repeat_from = :repeat_from,
+		    repeat_anchor = :repeat_anchor,
+		    date = :date,
+		    time = :time,
+		    duration = :duration
+		WHERE id = :id AND deleted_at = ''`
 
-	res, err := db.Exec(query,
+	res, err := s.db.Exec(query,
 		sql.Named("title", &task.Title),
 		sql.Named("comment", &task.Comment),
 		sql.Named("repeat", &task.Repeat),
+		sql.Named("repeat_until", &task.RepeatUntil),
+		sql.Named("repeat_count", &task.RepeatCount),
+		sql.Named("repeat_from", &task.RepeatFrom),
+		sql.Named("repeat_anchor", &task.RepeatAnchor),
 		sql.Named("date", &task.Date),
+		sql.Named("time", &task.Time),
+		sql.Named("duration", &task.Duration),
 		sql.Named("id", &task.ID),
 	)
 	if err != nil {
@@ -137,9 +295,10 @@ func UpdateTask(task *Task) error {
 	return nil
 }
 
-func DeleteTask(id string) error {
-	query := `DELETE FROM scheduler WHERE id = ?`
-	res, err := db.Exec(query, id)
+// DeleteTask переносит задачу в корзину. Даты-исключения остаются до окончательного удаления
+func (s *SQLiteStore) DeleteTask(id string, now time.Time) error {
+	res, err := s.db.Exec(`UPDATE scheduler SET deleted_at = ? WHERE id = ? AND deleted_at = ''`,
+		trashTime(now), id)
 	if err != nil {
 		return err
 	}
@@ -151,11 +310,10 @@ func DeleteTask(id string) error {
 	if count == 0 {
 		return fmt.Errorf(`incorrect id for deleting task`)
 	}
-
 	return nil
 }
 
-func UpdateTaskDate(id, nextDate string) error {
+func (s *SQLiteStore) UpdateTaskDate(id, nextDate, nextTime string) error {
 	if id == "" {
 		return errors.New("task id is empty")
 	}
@@ -164,9 +322,9 @@ func UpdateTaskDate(id, nextDate string) error {
 		return errors.New("task next date is empty")
 	}
 
-	query := `UPDATE scheduler SET date = :date WHERE id = :id`
+	query := `UPDATE scheduler SET date = :date, time = :time WHERE id = :id AND deleted_at = ''`
 
-	res, err := db.Exec(query, sql.Named("date", nextDate), sql.Named("id", id))
+	res, err := s.db.Exec(query, sql.Named("date", nextDate), sql.Named("time", nextTime), sql.Named("id", id))
 	if err != nil {
 		return err
 	}
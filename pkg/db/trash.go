@@ -0,0 +1,107 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// trashTime - время переноса в корзину в виде колонки deleted_at. Строки одной длины
+// в UTC сравниваются как время
+func trashTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Trash возвращает задачи из корзины, последние удалённые первыми
+func (s *SQLiteStore) Trash() ([]*Task, error) {
+	rows, err := s.db.Query(`SELECT id, date, time, duration, title, comment, repeat, repeat_until, repeat_count,
+		repeat_from, repeat_anchor, deleted_at
+		FROM scheduler WHERE deleted_at != '' ORDER BY deleted_at DESC, id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := make([]*Task, 0)
+	for rows.Next() {
+		var t Task
+		err = rows.Scan(&t.ID, &t.Date, &t.Time, &t.Duration, &t.Title, &t.Comment, &t.Repeat,
+			&t.RepeatUntil, &t.RepeatCount, &t.RepeatFrom, &t.RepeatAnchor, &t.DeletedAt)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, &t)
+	}
+	return tasks, rows.Err()
+}
+
+// RestoreTask возвращает задачу из корзины
+func (s *SQLiteStore) RestoreTask(id string) error {
+	res, err := s.db.Exec(`UPDATE scheduler SET deleted_at = '' WHERE id = ? AND deleted_at != ''`, id)
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return fmt.Errorf(`incorrect id for restoring task`)
+	}
+	return nil
+}
+
+// PurgeTask окончательно удаляет задачу из корзины вместе с её датами-исключениями
+func (s *SQLiteStore) PurgeTask(id string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM scheduler WHERE id = ? AND deleted_at != ''`, id)
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return fmt.Errorf(`incorrect id for purging task`)
+	}
+
+	_, err = tx.Exec(`DELETE FROM scheduler_exceptions WHERE task_id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// PurgeTrash окончательно удаляет задачи, попавшие в корзину раньше before, и возвращает их количество
+func (s *SQLiteStore) PurgeTrash(before time.Time) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM scheduler_exceptions WHERE task_id IN
+		(SELECT id FROM scheduler WHERE deleted_at != '' AND deleted_at < ?)`, trashTime(before))
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := tx.Exec(`DELETE FROM scheduler WHERE deleted_at != '' AND deleted_at < ?`, trashTime(before))
+	if err != nil {
+		return 0, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return count, tx.Commit()
+}
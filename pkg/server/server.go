@@ -1 +1,131 @@
 package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ezfroze/go_final_project/pkg/api"
+	"github.com/ezfroze/go_final_project/pkg/db"
+)
+
+// Config - настройки сервера планировщика
+type Config struct {
+	Port     string // порт HTTP-сервера
+	DBFile   string // файл базы данных SQLite
+	Password string // пароль для входа, пустой - без авторизации
+	Timezone string // часовой пояс по умолчанию из базы IANA, пустой - часовой пояс сервера
+	WebDir   string // каталог с файлами фронтенда, пустой - без фронтенда
+	// TrashDays - сколько дней удалённые задачи хранятся в корзине, 0 - без автоматической очистки
+	TrashDays int
+}
+
+// Значения настроек по умолчанию
+const (
+	DefaultPort      = "7540"
+	DefaultDBFile    = "scheduler.db"
+	DefaultWebDir    = "./web"
+	DefaultTrashDays = 30
+)
+
+// purgeInterval - как часто работающий сервер очищает корзину
+const purgeInterval = time.Hour
+
+// ConfigFromEnv читает настройки из переменных окружения TODO_PORT, TODO_DBFILE,
+// TODO_PASSWORD, TODO_TZ и TODO_TRASH_DAYS
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Port:      os.Getenv("TODO_PORT"),
+		DBFile:    os.Getenv("TODO_DBFILE"),
+		Password:  os.Getenv("TODO_PASSWORD"),
+		Timezone:  os.Getenv("TODO_TZ"),
+		WebDir:    DefaultWebDir,
+		TrashDays: DefaultTrashDays,
+	}
+
+	if cfg.Port == "" {
+		cfg.Port = DefaultPort
+	}
+	if cfg.DBFile == "" {
+		cfg.DBFile = DefaultDBFile
+	}
+	if days := os.Getenv("TODO_TRASH_DAYS"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("incorrect TODO_TRASH_DAYS %q", days)
+		}
+		cfg.TrashDays = n
+	}
+	return cfg, nil
+}
+
+// Server - сервер планировщика со своими хранилищем, часами и настройками.
+// Несколько серверов могут работать в одном процессе
+type Server struct {
+	cfg     Config
+	api     *api.API
+	handler http.Handler
+}
+
+// New собирает сервер из настроек, хранилища и часов. Пустые часы заменяются на time.Now.
+// Праздники загружаются из хранилища в производственный календарь сервера, корзина очищается
+// от задач с истёкшим сроком хранения
+func New(cfg Config, store db.Store, clock func() time.Time) (*Server, error) {
+	loc := time.Local
+	if cfg.Timezone != "" {
+		var err error
+		loc, err = time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	a := api.New(api.Options{
+		Store:     store,
+		Now:       clock,
+		Location:  loc,
+		Password:  cfg.Password,
+		TrashDays: cfg.TrashDays,
+	})
+	if err := a.LoadHolidays(); err != nil {
+		return nil, err
+	}
+	if _, err := a.PurgeTrash(); err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	if cfg.WebDir != "" {
+		mux.Handle("/", http.FileServer(http.Dir(cfg.WebDir)))
+	}
+	a.Register(mux)
+
+	return &Server{cfg: cfg, api: a, handler: mux}, nil
+}
+
+// Handler возвращает обработчик HTTP-запросов сервера
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// ListenAndServe запускает HTTP-сервер на порту из настроек и раз в purgeInterval очищает корзину
+func (s *Server) ListenAndServe() error {
+	if s.cfg.TrashDays > 0 {
+		go s.purgeTrash()
+	}
+	return http.ListenAndServe(fmt.Sprintf(":%s", s.cfg.Port), s.handler)
+}
+
+func (s *Server) purgeTrash() {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := s.api.PurgeTrash(); err != nil {
+			log.Printf("purge trash: %v", err)
+		}
+	}
+}
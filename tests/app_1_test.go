@@ -3,26 +3,67 @@ package tests
 import (
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/ezfroze/go_final_project/pkg/db"
+	"github.com/ezfroze/go_final_project/pkg/server"
 	"github.com/stretchr/testify/assert"
 )
 
-func getURL(path string) string {
-	port := Port
-	envPort := os.Getenv("TODO_PORT")
-	if len(envPort) > 0 {
-		if eport, err := strconv.ParseInt(envPort, 10, 32); err == nil {
-			port = int(eport)
+// serverURL - адрес сервера планировщика, который TestMain запускает в процессе тестов
+var serverURL string
+
+// TestMain запускает сервер через httptest на базе TODO_DBFILE или на временной базе,
+// поэтому тестам не нужен заранее запущенный сервер
+func TestMain(m *testing.M) {
+	os.Exit(runServer(m))
+}
+
+func runServer(m *testing.M) int {
+	if envFile := os.Getenv("TODO_DBFILE"); len(envFile) > 0 {
+		DBFile = envFile
+	} else {
+		dir, err := os.MkdirTemp("", "scheduler")
+		if err != nil {
+			log.Fatal(err)
 		}
+		defer os.RemoveAll(dir)
+		DBFile = filepath.Join(dir, "scheduler.db")
+	}
+
+	database, err := db.Init(DBFile)
+	if err != nil {
+		log.Fatal(err)
 	}
+	defer database.Close()
+
+	srv, err := server.New(server.Config{
+		DBFile:   DBFile,
+		Password: os.Getenv("TODO_PASSWORD"),
+		Timezone: os.Getenv("TODO_TZ"),
+		WebDir:   "../web",
+	}, db.NewSQLiteStore(database), time.Now)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+	serverURL = ts.URL
+
+	return m.Run()
+}
+
+func getURL(path string) string {
 	path = strings.TrimPrefix(strings.ReplaceAll(path, `\`, `/`), `../web/`)
-	return fmt.Sprintf("http://localhost:%d/%s", port, path)
+	return fmt.Sprintf("%s/%s", serverURL, path)
 }
 
 func getBody(path string) ([]byte, error) {
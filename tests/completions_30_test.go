@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ezfroze/go_final_project/pkg/db"
+	"github.com/ezfroze/go_final_project/pkg/server"
+	"github.com/stretchr/testify/assert"
+)
+
+type completionsResp struct {
+	Completions []map[string]any `json:"completions"`
+	Streak      *struct {
+		Current int `json:"current"`
+		Best    int `json:"best"`
+	} `json:"streak"`
+}
+
+func getCompletions(t *testing.T, url, query string) completionsResp {
+	code, body := serverDo(t, http.MethodGet, url+"/api/completions?"+query, "", nil)
+	assert.Equal(t, http.StatusOK, code, body)
+
+	var resp completionsResp
+	assert.NoError(t, json.Unmarshal([]byte(body), &resp), body)
+	return resp
+}
+
+func TestCompletions(t *testing.T) {
+	clock := time.Date(2024, 1, 26, 8, 0, 0, 0, time.UTC)
+	ts := newTestServer(t, server.Config{Timezone: "UTC"}, db.NewMemoryStore(), func() time.Time { return clock })
+
+	daily := serverAddTask(t, ts.URL, map[string]any{"date": "20240126", "title": "Зарядка", "repeat": "d 1"})
+	done := func(body string) {
+		t.Helper()
+		code, resp := serverDo(t, http.MethodPost, ts.URL+"/api/task/done?id="+daily, body, nil)
+		assert.Equal(t, http.StatusOK, code, resp)
+	}
+	streak := func(current, best int) {
+		t.Helper()
+		resp := getCompletions(t, ts.URL, "id="+daily)
+		if assert.NotNil(t, resp.Streak) {
+			assert.Equal(t, current, resp.Streak.Current, "current")
+			assert.Equal(t, best, resp.Streak.Best, "best")
+		}
+	}
+
+	streak(0, 0)
+	done(`{"note": " 10 минут "}`)
+	resp := getCompletions(t, ts.URL, "id="+daily)
+	if assert.Len(t, resp.Completions, 1) {
+		assert.Equal(t, map[string]any{
+			"id": "1", "task_id": daily, "title": "Зарядка", "date": "20240126", "done_date": "20240126",
+			"completed_at": "2024-01-26T08:00:00Z", "note": "10 минут", "next_date": "20240127",
+		}, resp.Completions[0])
+	}
+	streak(1, 1)
+
+	clock = clock.AddDate(0, 0, 1)
+	done("")
+	streak(2, 2)
+
+	// серия жива в день следующего повторения и прерывается, когда оно просрочено
+	clock = clock.AddDate(0, 0, 1)
+	streak(2, 2)
+	clock = clock.AddDate(0, 0, 1)
+	streak(0, 2)
+
+	// выполнение с опозданием серию не продолжает
+	done("")
+	streak(0, 2)
+	clock = clock.AddDate(0, 0, 1)
+	done("")
+	streak(1, 2)
+
+	// пропущенное повторение прерывает серию
+	code, body := serverDo(t, http.MethodPost, ts.URL+"/api/task/skip?id="+daily, "", nil)
+	assert.Equal(t, http.StatusOK, code, body)
+	streak(0, 2)
+	clock = clock.AddDate(0, 0, 2)
+	done("")
+	streak(1, 2)
+
+	// задача без повторения уходит в корзину, а запись о выполнении остаётся
+	once := serverAddTask(t, ts.URL, map[string]any{"date": "20240201", "title": "Разовая"})
+	code, body = serverDo(t, http.MethodPost, ts.URL+"/api/task/done?id="+once, "", nil)
+	assert.Equal(t, http.StatusOK, code, body)
+	code, body = serverDo(t, http.MethodDelete, ts.URL+"/api/trash?id="+once, "", nil)
+	assert.Equal(t, http.StatusOK, code, body)
+	resp = getCompletions(t, ts.URL, "id="+once)
+	assert.Nil(t, resp.Streak)
+	if assert.Len(t, resp.Completions, 1) {
+		assert.Equal(t, "Разовая", resp.Completions[0]["title"])
+		assert.Nil(t, resp.Completions[0]["next_date"])
+	}
+
+	// история всех задач, последние выполнения первыми
+	resp = getCompletions(t, ts.URL, "")
+	assert.Len(t, resp.Completions, 6)
+	assert.Nil(t, resp.Streak)
+	assert.Equal(t, once, resp.Completions[0]["task_id"])
+
+	resp = getCompletions(t, ts.URL, "from=20240127&to=20240129")
+	dates := make([]any, 0, len(resp.Completions))
+	for _, c := range resp.Completions {
+		dates = append(dates, c["done_date"])
+	}
+	assert.Equal(t, []any{"20240129", "20240127"}, dates)
+
+	// серии считаются по всей истории, даже если показана её часть
+	resp = getCompletions(t, ts.URL, "id="+daily+"&from=20240201")
+	assert.Len(t, resp.Completions, 1)
+	if assert.NotNil(t, resp.Streak) {
+		assert.Equal(t, 2, resp.Streak.Best)
+	}
+
+	for _, v := range []struct {
+		method, path, body string
+		code               int
+	}{
+		{http.MethodGet, "/api/completions?from=2024-01-01", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/completions?to=abc", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/completions?from=20240201&to=20240101", "", http.StatusBadRequest},
+		{http.MethodPost, "/api/completions", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/api/task/done?id=" + daily, `{"note": 5}`, http.StatusBadRequest},
+	} {
+		code, body = serverDo(t, v.method, ts.URL+v.path, v.body, nil)
+		assert.Equal(t, v.code, code, "%s %s", v.method, v.path)
+		assert.Contains(t, body, `"error"`, "%s %s", v.method, v.path)
+	}
+	// ошибка в теле запроса не отмечает задачу выполненной
+	assert.Len(t, getCompletions(t, ts.URL, "id="+daily).Completions, 5)
+}
+
+func TestCompletionsTimezone(t *testing.T) {
+	clock := time.Date(2024, 1, 26, 22, 30, 0, 0, time.UTC)
+	ts := newTestServer(t, server.Config{Timezone: "Europe/Moscow"}, db.NewMemoryStore(), fixedClock(clock))
+
+	// день выполнения - в часовом поясе пользователя, момент выполнения - в UTC
+	id := serverAddTask(t, ts.URL, map[string]any{"date": "20240127", "title": "Ночная"})
+	code, body := serverDo(t, http.MethodPost, ts.URL+"/api/task/done?id="+id, "", nil)
+	assert.Equal(t, http.StatusOK, code, body)
+
+	resp := getCompletions(t, ts.URL, "from=20240127&to=20240127")
+	if assert.Len(t, resp.Completions, 1) {
+		assert.Equal(t, "20240127", resp.Completions[0]["done_date"])
+		assert.Equal(t, "2024-01-26T22:30:00Z", resp.Completions[0]["completed_at"])
+	}
+}
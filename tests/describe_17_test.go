@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func describe(t *testing.T, repeat, lang string) map[string]string {
+	body, err := getBody(fmt.Sprintf("api/repeat/describe?repeat=%s&lang=%s", url.QueryEscape(repeat), lang))
+	assert.NoError(t, err)
+	var m map[string]string
+	assert.NoError(t, json.Unmarshal(body, &m))
+	return m
+}
+
+func TestDescribeRepeat(t *testing.T) {
+	tbl := []struct {
+		repeat, ru, en string
+	}{
+		{"d 1", "каждый день", "every day"},
+		{"d 3", "каждые 3 дня", "every 3 days"},
+		{"d 21", "каждый 21 день", "every 21 days"},
+		{"d 7 fwd", "каждые 7 дней, выходные переносятся на следующий рабочий день",
+			"every 7 days, moved to the next business day if it falls on a day off"},
+		{"y", "каждый год", "every year"},
+		{"h 4", "каждые 4 часа", "every 4 hours"},
+		{"b 5", "каждые 5 рабочих дней", "every 5 business days"},
+		{"w 1,4", "по понедельникам и четвергам", "every week on Monday and Thursday"},
+		{"w 5 2", "каждые 2 недели по пятницам", "every 2 weeks on Friday"},
+		{"m 1,-1 2,8", "1-го числа и в последний день февраля и августа",
+			"on the 1st and last day of February and August"},
+		{"m 15", "каждый месяц 15-го числа", "every month on the 15th"},
+		{"m 2tu", "каждый месяц во второй вторник", "every month on the second Tuesday"},
+		{"m -1fr 3,9", "в последнюю пятницу марта и сентября", "on the last Friday of March and September"},
+		{"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1",
+			"каждый месяц по понедельникам, вторникам, средам, четвергам и пятницам, только последний из этих дней",
+			"every month on every Monday, Tuesday, Wednesday, Thursday and Friday, only the last of these days"},
+		{"FREQ=DAILY;COUNT=3", "каждый день, всего 3 раза", "every day, 3 times"},
+		{"FREQ=WEEKLY;UNTIL=20240301", "каждую неделю, до 01.03.2024", "every week, until March 1, 2024"},
+	}
+	for _, v := range tbl {
+		assert.Equal(t, v.ru, describe(t, v.repeat, "ru")["description"], v.repeat)
+		assert.Equal(t, v.en, describe(t, v.repeat, "en")["description"], v.repeat)
+	}
+
+	assert.NotEmpty(t, describe(t, "m 30 40", "ru")["error"])
+	assert.NotEmpty(t, describe(t, "d 1", "de")["error"])
+}
+
+func TestTaskRepeatDescription(t *testing.T) {
+	m, err := postJSON("api/task", map[string]any{
+		"date":   time.Now().Format(`20060102`),
+		"title":  "Зарплата",
+		"repeat": "m 10,25",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(m["id"])
+
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var task map[string]any
+	assert.NoError(t, json.Unmarshal(body, &task))
+	assert.Equal(t, "каждый месяц 10-го и 25-го числа", task["repeat_description"])
+
+	body, err = requestJSON("api/task?lang=en&id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(body, &task))
+	assert.Equal(t, "every month on the 10th and 25th", task["repeat_description"])
+}
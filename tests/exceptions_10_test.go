@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getExceptions(t *testing.T, id string) []string {
+	body, err := requestJSON("api/task/exceptions?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string][]string
+	err = json.Unmarshal(body, &m)
+	assert.NoError(t, err)
+	return m["exceptions"]
+}
+
+func TestSkipTask(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	today := now.Format(`20060102`)
+
+	id := addTask(t, task{
+		title: "Разовая задача",
+	})
+	m, err := postJSON("api/task/skip?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, m["error"])
+
+	m, err = postJSON("api/task/skip", nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, m["error"])
+
+	id = addTask(t, task{
+		date:   today,
+		title:  "Полить цветы",
+		repeat: "d 1",
+	})
+
+	m, err = postJSON("api/task/skip?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 1).Format(`20060102`), m["date"])
+	assert.Equal(t, []string{today}, getExceptions(t, id))
+
+	var stored Task
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 1).Format(`20060102`), stored.Date)
+
+	holiday := now.AddDate(0, 0, 2).Format(`20060102`)
+	m, err = postJSON("api/task/exceptions?id="+id+"&date="+holiday, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Nil(t, m["error"])
+	assert.Equal(t, []string{today, holiday}, getExceptions(t, id))
+
+	m, err = postJSON("api/task/exceptions?id="+id+"&date=ooops", nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, m["error"])
+
+	ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 3).Format(`20060102`), stored.Date)
+
+	m, err = postJSON("api/task/exceptions?id="+id+"&date="+today, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Nil(t, m["error"])
+	assert.Equal(t, []string{holiday}, getExceptions(t, id))
+
+	m, err = postJSON("api/task/exceptions?id="+id+"&date="+today, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, m["error"])
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	// исключения удаляются, когда задача удаляется из корзины
+	ret, err = postJSON("api/trash?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	var count int
+	err = db.Get(&count, `SELECT count(*) FROM scheduler_exceptions WHERE task_id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
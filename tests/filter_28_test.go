@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ezfroze/go_final_project/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func filterTasks(t *testing.T, filter string) []string {
+	page := getTasksPage(t, url.Values{"filter": {filter}})
+	return searchTitles(page.Tasks)
+}
+
+func TestTasksFilter(t *testing.T) {
+	now := time.Now()
+	from := now.AddDate(0, 0, 60)
+	ids := []string{
+		addTask(t, task{date: from.Format(`20060102`), title: "Фильтр счёт", repeat: "d 5"}),
+		addTask(t, task{date: from.AddDate(0, 0, 1).Format(`20060102`), title: "Фильтр отчёт", comment: "приложить счёт"}),
+		addTask(t, task{date: from.AddDate(0, 0, 10).Format(`20060102`), title: "Фильтр отпуск"}),
+	}
+	dates := "date>=" + from.Format(`20060102`) + " date<" + from.AddDate(0, 0, 10).Format(`02.01.2006`)
+
+	assert.Equal(t, []string{"Фильтр счёт", "Фильтр отчёт"}, filterTasks(t, dates))
+	assert.Equal(t, []string{"Фильтр счёт"}, filterTasks(t, dates+" repeat:yes"))
+	assert.Equal(t, []string{"Фильтр отчёт"}, filterTasks(t, dates+" -repeat:yes"))
+	assert.Equal(t, []string{"Фильтр счёт", "Фильтр отчёт", "Фильтр отпуск"}, filterTasks(t, `text:"фильтр"`))
+	assert.Equal(t, []string{"Фильтр счёт"}, filterTasks(t, "title:фильтр title:счёт"))
+	assert.Equal(t, []string{"Фильтр отчёт"}, filterTasks(t, `comment:"приложить счёт"`))
+	assert.Equal(t, []string{"Фильтр счёт", "Фильтр отпуск"},
+		filterTasks(t, "title:фильтр (repeat:yes OR NOT comment:счёт)"))
+	assert.Empty(t, filterTasks(t, "title:фильтр overdue:yes"))
+
+	// фильтр вместе с поиском по словам
+	page := getTasksPage(t, url.Values{"search": {"счёт"}, "filter": {"title:фильтр repeat:no"}})
+	assert.Equal(t, []string{"Фильтр отчёт"}, searchTitles(page.Tasks))
+	assert.Equal(t, 1, page.Total)
+
+	for _, v := range []struct {
+		filter string
+		err    string
+	}{
+		{"tag:work", "filter: unknown field tag, supported fields: comment, date, overdue, repeat, text, title at position 1"},
+		{"repeat:yes -tags:home", "filter: unknown field tags, supported fields: comment, date, overdue, repeat, text, title at position 13"},
+		{"date>=2025-01-01", "filter: invalid date 2025-01-01 at position 1"},
+		{"repeat:maybe", "filter: repeat expects yes or no at position 1"},
+		{"repeat>yes", "filter: field repeat does not support > at position 1"},
+		{"date>=", "filter: missing value for date at position 1"},
+		{"(repeat:yes OR overdue:yes", "filter: missing ) at position 1"},
+		{"repeat:yes)", "filter: unexpected ) at position 11"},
+		{"repeat:yes OR", "filter: missing condition at position 14"},
+		{"AND repeat:yes", "filter: unexpected AND at position 1"},
+		{`text:"счёт`, "filter: unterminated quote at position 6"},
+		{"title:...", "filter: no words to search in title:... at position 1"},
+	} {
+		body, err := requestJSON("api/tasks?filter="+url.QueryEscape(v.filter), nil, http.MethodGet)
+		assert.NoError(t, err)
+		var m map[string]any
+		assert.NoError(t, json.Unmarshal(body, &m))
+		assert.Equal(t, v.err, m["error"], v.filter)
+	}
+
+	// фильтр из тела формы не читается
+	req, err := http.NewRequest(http.MethodPost, getURL("api/tasks"),
+		strings.NewReader(url.Values{"filter": {"tag:work"}}.Encode()))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if len(Token) > 0 {
+		req.AddCookie(&http.Cookie{Name: "token", Value: Token})
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	for _, id := range ids {
+		_, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	filter, err := db.ParseFilter("  ")
+	assert.NoError(t, err)
+	assert.Nil(t, filter)
+
+	// тегов нет, метку ищут как слово
+	_, err = db.ParseFilter("text:#работа")
+	assert.NoError(t, err)
+
+	// вложенность скобок и NOT и длина выражения ограничены
+	_, err = db.ParseFilter(strings.Repeat("(", 64) + "repeat:yes" + strings.Repeat(")", 64))
+	assert.NoError(t, err)
+	_, err = db.ParseFilter(strings.Repeat("NOT ", 64) + "repeat:yes")
+	assert.NoError(t, err)
+	for _, deep := range []string{
+		strings.Repeat("(", 65) + "repeat:yes" + strings.Repeat(")", 65),
+		strings.Repeat("NOT ", 33) + strings.Repeat("(", 32) + "repeat:yes" + strings.Repeat(")", 32),
+		strings.Repeat("(", 3000000),
+	} {
+		_, err = db.ParseFilter(deep)
+		assert.Error(t, err)
+	}
+	_, err = db.ParseFilter(strings.Repeat("NOT ", 65) + "repeat:yes")
+	assert.EqualError(t, err, "filter: nesting deeper than 64 at position 257")
+	_, err = db.ParseFilter(strings.Repeat("a", 1001))
+	assert.EqualError(t, err, "filter: expression longer than 1000 characters at position 1001")
+
+	_, err = db.ParseFilter("repeat:yes  overdue:maybe")
+	var filterErr *db.FilterError
+	if assert.ErrorAs(t, err, &filterErr) {
+		assert.Equal(t, 13, filterErr.Pos)
+	}
+}
@@ -0,0 +1,220 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ezfroze/go_final_project/pkg/api"
+	"github.com/ezfroze/go_final_project/pkg/db"
+	"github.com/ezfroze/go_final_project/pkg/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBusinessDayCalendar(t *testing.T) {
+	// новогодние каникулы
+	cal := api.NewHolidayList([]string{
+		"20240108", "20240101", "20240102", "20240103", "20240104", "20240105",
+	})
+
+	tbl := []struct {
+		now, date, repeat, want string
+	}{
+		{"20231229", "20231229", "b 1", "20240109"},
+		{"20240110", "20231225", "b 5", "20240116"},
+		{"20231215", "20231201", "m 1 fwd", "20240109"},
+		{"20231215", "20231201", "m 1 back", "20231229"},
+		{"20231201", "20230101", "y fwd", "20240109"},
+	}
+	for _, v := range tbl {
+		now, err := time.Parse(`20060102`, v.now)
+		assert.NoError(t, err)
+		next, _, err := api.NextDateTime(now, v.date, "", v.repeat, cal)
+		assert.NoError(t, err, "%v", v)
+		assert.Equal(t, v.want, next, "%v", v)
+	}
+
+	// без календаря праздники не учитываются
+	next, err := api.NextDate(time.Date(2023, 12, 29, 0, 0, 0, 0, time.UTC), "20231229", "b 1")
+	assert.NoError(t, err)
+	assert.Equal(t, "20240101", next)
+}
+
+func nextDateBody(t *testing.T, now, date, repeat string) string {
+	body, err := getBody(fmt.Sprintf("api/nextdate?now=%s&date=%s&repeat=%s", now, date, url.QueryEscape(repeat)))
+	assert.NoError(t, err)
+	return strings.TrimSpace(string(body))
+}
+
+func postICS(t *testing.T, ics string) map[string]any {
+	req, err := http.NewRequest(http.MethodPost, getURL("api/holidays/import"), bytes.NewBufferString(ics))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "text/calendar")
+	if len(Token) > 0 {
+		req.AddCookie(&http.Cookie{Name: "token", Value: Token})
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	return m
+}
+
+func TestHolidays(t *testing.T) {
+	ret, err := postJSON("api/holidays", map[string]any{"date": "20310106", "title": "Отгул"}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	ret, err = postJSON("api/holidays", map[string]any{"date": "2031-01-06"}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	assert.Equal(t, "20310107", nextDateBody(t, "20310103", "20310103", "b 1"))
+
+	ics := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART;VALUE=DATE:20310501\r\n" +
+		"DTEND;VALUE=DATE:20310503\r\n" +
+		"SUMMARY:Праздник\r\n  весны и труда\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"DTSTART;VALUE=DATE:20310101\r\n" +
+		"RRULE:FREQ=YEARLY;COUNT=2\r\n" +
+		"SUMMARY:Новый год\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	ret = postICS(t, ics)
+	assert.Equal(t, float64(4), ret["imported"])
+
+	ret = postICS(t, "BEGIN:VEVENT\r\nSUMMARY:Без даты\r\nEND:VEVENT\r\n")
+	assert.NotEmpty(t, ret["error"])
+
+	body, err := requestJSON("api/holidays", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var resp struct {
+		Holidays []map[string]string `json:"holidays"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &resp))
+	titles := make(map[string]string)
+	for _, h := range resp.Holidays {
+		titles[h["date"]] = h["title"]
+	}
+	assert.Equal(t, "Праздник весны и труда", titles["20310502"])
+	assert.Equal(t, "Новый год", titles["20320101"])
+	assert.Equal(t, "Отгул", titles["20310106"])
+
+	assert.Equal(t, "20310505", nextDateBody(t, "20310430", "20310430", "b 1"))
+	assert.Equal(t, "20310430", nextDateBody(t, "20310401", "20310101", "m 1 5 back"))
+
+	for _, date := range []string{"20310106", "20310501", "20310502", "20310101", "20320101"} {
+		ret, err = postJSON("api/holidays?date="+date, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+	ret, err = postJSON("api/holidays?date=20310106", nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	assert.Equal(t, "20310106", nextDateBody(t, "20310103", "20310103", "b 1"))
+}
+
+func TestHolidaysImportRepeat(t *testing.T) {
+	event := func(rrule string) string {
+		return "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20250101\r\n" +
+			"RRULE:" + rrule + "\r\nSUMMARY:Событие\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	}
+	importICS := func(rrule string) (int, map[string]any) {
+		ts := newTestServer(t, server.Config{Timezone: "UTC"}, db.NewMemoryStore(), nil)
+		code, body := serverDo(t, http.MethodPost, ts.URL+"/api/holidays/import", event(rrule), nil)
+		var m map[string]any
+		assert.NoError(t, json.Unmarshal([]byte(body), &m), body)
+		return code, m
+	}
+
+	// праздники - целые дни, повторения чаще раза в день не принимаются
+	code, m := importICS("FREQ=HOURLY")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.NotEmpty(t, m["error"])
+
+	// большой COUNT обходится за один проход и ограничен icsRepeatYears годами
+	for _, v := range []struct {
+		rrule    string
+		imported float64
+	}{
+		{"FREQ=DAILY;COUNT=10000", 3653},
+		{"FREQ=DAILY", 3653},
+		{"FREQ=WEEKLY;COUNT=3", 3},
+		{"FREQ=MONTHLY;BYMONTHDAY=31;COUNT=10000", 71},
+	} {
+		start := time.Now()
+		code, m = importICS(v.rrule)
+		assert.Less(t, time.Since(start), time.Second, v.rrule)
+		assert.Equal(t, http.StatusOK, code, v.rrule)
+		assert.Equal(t, v.imported, m["imported"], v.rrule)
+	}
+}
+
+func TestTaskRoll(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	// суббота не раньше чем через неделю
+	saturday := time.Now().AddDate(0, 0, 7)
+	for saturday.Weekday() != time.Saturday {
+		saturday = saturday.AddDate(0, 0, 1)
+	}
+	date := func(days int) string {
+		return saturday.AddDate(0, 0, days).Format(`20060102`)
+	}
+
+	m, err := postJSON("api/task", map[string]any{
+		"date":   date(0),
+		"title":  "Отчёт через три дня",
+		"repeat": "d 3 fwd",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(m["id"])
+
+	var stored Task
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, date(2), stored.Date)
+	assert.Equal(t, date(0), stored.RepeatAnchor)
+
+	// серия считается от субботы: следующий день - вторник, а не четверг
+	ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, date(3), stored.Date)
+	assert.Equal(t, date(0), stored.RepeatAnchor)
+
+	// новая дата не из серии начинает серию заново
+	ret, err = postJSON("api/task", map[string]any{
+		"id":            id,
+		"date":          date(7),
+		"title":         "Отчёт через три дня",
+		"repeat":        "d 3 fwd",
+		"repeat_anchor": date(0),
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, date(9), stored.Date)
+	assert.Equal(t, date(7), stored.RepeatAnchor)
+}
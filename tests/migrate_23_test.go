@@ -0,0 +1,179 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/ezfroze/go_final_project/pkg/db"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func latestVersion() int {
+	migrations := db.Migrations()
+	return migrations[len(migrations)-1].Version
+}
+
+func TestMigrateOldDatabase(t *testing.T) {
+	dbfile := filepath.Join(t.TempDir(), "scheduler.db")
+
+	fixture, err := os.ReadFile("testdata/scheduler_v1.sql")
+	assert.NoError(t, err)
+	old, err := sqlx.Connect("sqlite", dbfile)
+	assert.NoError(t, err)
+	_, err = old.Exec(string(fixture))
+	assert.NoError(t, err)
+	assert.NoError(t, old.Close())
+
+	pending, err := db.PendingMigrations(dbfile)
+	assert.NoError(t, err)
+	assert.Len(t, pending, len(db.Migrations()))
+
+	database, err := db.Init(dbfile)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer database.Close()
+
+	version, err := db.SchemaVersion(database)
+	assert.NoError(t, err)
+	assert.Equal(t, latestVersion(), version)
+
+	pending, err = db.PendingMigrations(dbfile)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+
+	// старые задачи сохраняются, новые колонки получают значения по умолчанию
+	var tasks []Task
+	err = sqlx.NewDb(database, "sqlite").Select(&tasks, `SELECT * FROM scheduler ORDER BY id`)
+	assert.NoError(t, err)
+	if assert.Len(t, tasks, 3) {
+		assert.Equal(t, Task{ID: 1, Date: "20240126", Title: "Фитнес", Comment: "Тренировка", Repeat: "d 2"}, tasks[0])
+		assert.Equal(t, "m 1", tasks[1].Repeat)
+		assert.Equal(t, "Глава 3", tasks[2].Comment)
+	}
+
+	// новые таблицы и колонки работают
+	store := db.NewSQLiteStore(database)
+	id, err := store.AddTask(&db.Task{Date: "20240401", Time: "09:30", Title: "Созвон", Repeat: "d 7", RepeatCount: 3})
+	assert.NoError(t, err)
+	stored, err := store.GetTask(strconv.FormatInt(id, 10))
+	assert.NoError(t, err)
+	assert.Equal(t, "09:30", stored.Time)
+	assert.Equal(t, 3, stored.RepeatCount)
+
+	assert.NoError(t, store.AddHolidays([]db.Holiday{{Date: "20240501", Title: "Праздник"}}))
+	holidays, err := store.Holidays()
+	assert.NoError(t, err)
+	assert.Len(t, holidays, 1)
+
+	// повторный запуск ничего не меняет
+	assert.NoError(t, db.Migrate(database))
+	var applied int
+	assert.NoError(t, database.QueryRow(`SELECT count(*) FROM schema_version`).Scan(&applied))
+	assert.Equal(t, len(db.Migrations()), applied)
+}
+
+// База, созданная до появления миграций с полной схемой, тоже обновляется
+func TestMigrateUnversionedDatabase(t *testing.T) {
+	dbfile := filepath.Join(t.TempDir(), "scheduler.db")
+
+	old, err := sqlx.Connect("sqlite", dbfile)
+	assert.NoError(t, err)
+	_, err = old.Exec(`CREATE TABLE scheduler (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		date CHAR(8) NOT NULL DEFAULT '',
+		time CHAR(5) NOT NULL DEFAULT '',
+		duration INTEGER NOT NULL DEFAULT 0,
+		title TEXT NOT NULL DEFAULT '',
+		comment TEXT NOT NULL DEFAULT '',
+		repeat CHAR(128) NOT NULL DEFAULT '',
+		repeat_until CHAR(8) NOT NULL DEFAULT '',
+		repeat_count INTEGER NOT NULL DEFAULT 0,
+		repeat_from CHAR(8) NOT NULL DEFAULT '',
+		repeat_anchor CHAR(8) NOT NULL DEFAULT ''
+	);
+	INSERT INTO scheduler (date, time, title, repeat) VALUES ('20240126', '18:00', 'Бассейн', 'w 2,5')`)
+	assert.NoError(t, err)
+	assert.NoError(t, old.Close())
+
+	database, err := db.Init(dbfile)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer database.Close()
+
+	version, err := db.SchemaVersion(database)
+	assert.NoError(t, err)
+	assert.Equal(t, latestVersion(), version)
+
+	stored, err := db.NewSQLiteStore(database).GetTask("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "18:00", stored.Time)
+	assert.Equal(t, "w 2,5", stored.Repeat)
+}
+
+// Базы, созданные промежуточными версиями схемы до появления миграций, получают
+// недостающие колонки и таблицы
+func TestMigrateIntermediateSchemas(t *testing.T) {
+	base := `id INTEGER PRIMARY KEY AUTOINCREMENT,
+		date CHAR(8) NOT NULL DEFAULT '',
+		title TEXT NOT NULL DEFAULT '',
+		comment TEXT NOT NULL DEFAULT '',
+		repeat CHAR(128) NOT NULL DEFAULT '',
+		repeat_until CHAR(8) NOT NULL DEFAULT '',
+		repeat_count INTEGER NOT NULL DEFAULT 0`
+	exceptions := `CREATE TABLE scheduler_exceptions (task_id INTEGER NOT NULL, date CHAR(8) NOT NULL,
+		PRIMARY KEY (task_id, date));`
+	for _, v := range []struct {
+		name   string
+		schema string
+	}{
+		{"repeat end conditions", `CREATE TABLE scheduler (` + base + `);`},
+		{"repeat exceptions", `CREATE TABLE scheduler (` + base + `);` + exceptions},
+		{"repeat from completion", `CREATE TABLE scheduler (` + base + `,
+			repeat_from CHAR(8) NOT NULL DEFAULT '');` + exceptions},
+		{"task time and duration", `CREATE TABLE scheduler (` + base + `,
+			repeat_from CHAR(8) NOT NULL DEFAULT '',
+			time CHAR(5) NOT NULL DEFAULT '',
+			duration INTEGER NOT NULL DEFAULT 0);` + exceptions},
+	} {
+		dbfile := filepath.Join(t.TempDir(), "scheduler.db")
+		old, err := sqlx.Connect("sqlite", dbfile)
+		assert.NoError(t, err, v.name)
+		_, err = old.Exec(v.schema + `INSERT INTO scheduler (date, title, repeat, repeat_count)
+			VALUES ('20240126', 'Полив', 'd 3', 4)`)
+		assert.NoError(t, err, v.name)
+		assert.NoError(t, old.Close(), v.name)
+
+		database, err := db.Init(dbfile)
+		if !assert.NoError(t, err, v.name) {
+			continue
+		}
+
+		version, err := db.SchemaVersion(database)
+		assert.NoError(t, err, v.name)
+		assert.Equal(t, latestVersion(), version, v.name)
+
+		store := db.NewSQLiteStore(database)
+		stored, err := store.GetTask("1")
+		if assert.NoError(t, err, v.name) {
+			assert.Equal(t, "d 3", stored.Repeat, v.name)
+			assert.Equal(t, 4, stored.RepeatCount, v.name)
+		}
+
+		stored.Time, stored.Duration = "07:00", 15
+		stored.RepeatFrom, stored.RepeatAnchor = "done", "20240125"
+		assert.NoError(t, store.UpdateTask(stored), v.name)
+		updated, err := store.GetTask("1")
+		if assert.NoError(t, err, v.name) {
+			assert.Equal(t, stored, updated, v.name)
+		}
+
+		assert.NoError(t, store.AddException("1", "20240129"), v.name)
+		assert.NoError(t, store.AddHolidays([]db.Holiday{{Date: "20240501"}}), v.name)
+		assert.NoError(t, database.Close(), v.name)
+	}
+}
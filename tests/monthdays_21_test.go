@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ezfroze/go_final_project/pkg/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDateMonthOffsets(t *testing.T) {
+	now := time.Date(2024, 1, 26, 0, 0, 0, 0, time.UTC)
+
+	tbl := []nextDate{
+		{"20240101", "m -3", "20240129"},
+		{"20240201", "m -3", "20240227"},
+		{"20240101", "m -6", "20240224"},
+		{"20240101", "m -6,-1", "20240131"},
+		{"20240101", "m -31", "20240301"},
+		{"20240101", "m -30 2", ""},
+		{"20240101", "m -29 2", "20240201"},
+		{"20240101", "m -32", ""},
+		{"20240101", "FREQ=MONTHLY;BYMONTHDAY=-3", "20240129"},
+		{"20240101", "FREQ=MONTHLY;BYMONTHDAY=-32", ""},
+		// clamp
+		{"20240201", "m 31 clamp", "20240229"},
+		{"20240301", "m 31 clamp", "20240331"},
+		{"20240401", "m 31 4,6,9,11 clamp", "20240430"},
+		{"20240101", "m 30 2 clamp", "20240229"},
+		{"20250101", "m 30 2 clamp", "20250228"},
+		{"20240201", "m -31 clamp", "20240201"},
+		{"20240201", "m 31 clamp fwd", "20240229"},
+		{"20240101", "m 31 fwd clamp", ""},
+		{"20240101", "d 31 clamp", ""},
+		{"20240101", "w 1 clamp", ""},
+	}
+	for _, v := range tbl {
+		next, err := api.NextDate(now, v.date, v.repeat)
+		if v.want == "" {
+			assert.Error(t, err, "%q %q", v.date, v.repeat)
+			continue
+		}
+		assert.NoError(t, err, "%q %q", v.date, v.repeat)
+		assert.Equal(t, v.want, next, "%q %q", v.date, v.repeat)
+	}
+}
+
+func TestMonthOffsetsConvertAndDescribe(t *testing.T) {
+	rule, err := api.RuleFromRepeat("m 31,-5 clamp")
+	assert.NoError(t, err)
+	assert.True(t, rule.Clamp)
+	assert.Equal(t, []int{31, -5}, rule.Days)
+	repeat, err := api.RepeatFromRule(rule)
+	assert.NoError(t, err)
+	assert.Equal(t, "m 31,-5 clamp", repeat)
+
+	tbl := []struct {
+		repeat, ru, en string
+	}{
+		{"m -3", "каждый месяц в 3-й день с конца", "every month on the third to last day"},
+		{"m -10 1,7", "в 10-й день с конца января и июля", "on the 10th to last day of January and July"},
+		{"m 31 clamp", "каждый месяц 31-го числа, в коротких месяцах - в последний день",
+			"every month on the 31st, on the last day in shorter months"},
+		{"m 31,-31 clamp", "каждый месяц 31-го числа и в 31-й день с конца, в коротких месяцах - в последний или первый день",
+			"every month on the 31st and 31st to last day, on the last or first day in shorter months"},
+	}
+	for _, v := range tbl {
+		ru, err := api.DescribeRepeat(v.repeat, api.LangRU)
+		assert.NoError(t, err, v.repeat)
+		assert.Equal(t, v.ru, ru, v.repeat)
+		en, err := api.DescribeRepeat(v.repeat, api.LangEN)
+		assert.NoError(t, err, v.repeat)
+		assert.Equal(t, v.en, en, v.repeat)
+	}
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ezfroze/go_final_project/pkg/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDateBounded(t *testing.T) {
+	now := time.Date(2024, 1, 26, 0, 0, 0, 0, time.UTC)
+
+	tbl := []nextDate{
+		{"19040101", "d 1", "20240127"},
+		{"19040101", "d 400", "20240619"},
+		{"10000101", "y", "20250101"},
+		{"19040105", "w 5 52", "20240809"},
+		{"19040101", "m -1", "20240131"},
+		{"20240101", "m 29 2", "20240229"},
+		{"20240301", "m 29 2", "20280229"},
+		{"20240101", "m 5mo 2", "20440229"},
+		{"20240101", "FREQ=MONTHLY;INTERVAL=12;BYMONTH=6", ""},
+		{"20240101", "FREQ=MONTHLY;BYMONTHDAY=1,2;BYSETPOS=3", ""},
+		{"20240101", "m 30 2", ""},
+		{"20240101", "m 31 4,6,9,11", ""},
+	}
+	for _, v := range tbl {
+		next, err := api.NextDate(now, v.date, v.repeat)
+		if v.want == "" {
+			assert.Error(t, err, "%q %q", v.date, v.repeat)
+			continue
+		}
+		assert.NoError(t, err, "%q %q", v.date, v.repeat)
+		assert.Equal(t, v.want, next, "%q %q", v.date, v.repeat)
+	}
+}
+
+// Серия с COUNT обходится от даты начала, поэтому COUNT ограничен
+func TestNextDateCountBounded(t *testing.T) {
+	now := time.Date(2024, 1, 26, 0, 0, 0, 0, time.UTC)
+
+	for _, repeat := range []string{
+		"FREQ=HOURLY;COUNT=10000",
+		"FREQ=DAILY;COUNT=10000",
+		"FREQ=MONTHLY;BYMONTHDAY=31;COUNT=10000",
+	} {
+		start := time.Now()
+		_, err := api.NextDate(now, "00010101", repeat)
+		assert.ErrorIs(t, err, api.ErrRepeatEnded, repeat)
+		_, err = api.NextDates(now, "00010101", "", repeat, 10, nil)
+		assert.ErrorIs(t, err, api.ErrRepeatEnded, repeat)
+		assert.Less(t, time.Since(start), time.Second, repeat)
+	}
+
+	for _, repeat := range []string{"FREQ=HOURLY;COUNT=10001", "FREQ=HOURLY;COUNT=2000000000"} {
+		_, err := api.NextDate(now, "00010101", repeat)
+		assert.Error(t, err, repeat)
+	}
+}
+
+// Следующая дата после 9999 года не помещается в YYYYMMDD ни для одного правила
+func TestNextDateOutOfRange(t *testing.T) {
+	now := time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+	for _, repeat := range []string{"d 400", "d 1", "y", "y 3", "w 1", "m 1", "b 1", "FREQ=YEARLY", "m 31 fwd"} {
+		_, err := api.NextDate(now, "99991231", repeat)
+		assert.ErrorIs(t, err, api.ErrDateOutOfRange, repeat)
+	}
+
+	_, _, err := api.NextDateTime(time.Date(9999, 12, 31, 23, 30, 0, 0, time.UTC), "99991231", "23:00", "h 1", nil)
+	assert.ErrorIs(t, err, api.ErrDateOutOfRange)
+
+	next, err := api.NextDate(time.Date(9999, 12, 30, 0, 0, 0, 0, time.UTC), "99991201", "d 1")
+	assert.NoError(t, err)
+	assert.Equal(t, "99991231", next)
+}
+
+func benchmarkNextDate(b *testing.B, dstart, repeat string) {
+	now := time.Date(2024, 1, 26, 0, 0, 0, 0, time.UTC)
+	for b.Loop() {
+		_, _ = api.NextDate(now, dstart, repeat)
+	}
+}
+
+// Стоимость не должна зависеть от того, как давно начата задача
+
+func BenchmarkNextDateDayRecent(b *testing.B)    { benchmarkNextDate(b, "20240101", "d 1") }
+func BenchmarkNextDateDayAncient(b *testing.B)   { benchmarkNextDate(b, "10000101", "d 1") }
+func BenchmarkNextDateYearAncient(b *testing.B)  { benchmarkNextDate(b, "10000101", "y") }
+func BenchmarkNextDateWeekAncient(b *testing.B)  { benchmarkNextDate(b, "10000101", "w 1,4 2") }
+func BenchmarkNextDateMonthAncient(b *testing.B) { benchmarkNextDate(b, "10000101", "m -1fr 3,9") }
+
+// Правила без повторений отсекаются сразу или после перебора одного цикла календаря
+
+func BenchmarkNextDateImpossible(b *testing.B) { benchmarkNextDate(b, "20240101", "m 30 2") }
+func BenchmarkNextDateImpossibleInterval(b *testing.B) {
+	benchmarkNextDate(b, "20240101", "FREQ=MONTHLY;INTERVAL=12;BYMONTH=6")
+}
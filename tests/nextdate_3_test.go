@@ -67,7 +67,7 @@ func TestNextDate(t *testing.T) {
 		{"20230311", "m 1 1,2", "20240201"},
 		{"20240127", "m -1", "20240131"},
 		{"20240222", "m -2", "20240228"},
-		{"20240222", "m -2,-3", ""},
+		{"20240222", "m -2,-3", "20240227"},
 		{"20240326", "m -1,-2", "20240330"},
 		{"20240201", "m -1,18", "20240218"},
 		{"20240125", "w 1,2,3", "20240129"},
@@ -76,4 +76,88 @@ func TestNextDate(t *testing.T) {
 		{"20230226", "w 8,4,5", ""},
 	}
 	check()
+	tbl = []nextDate{
+		{"20240101", "w 1,4 2", "20240129"},
+		{"20240108", "w 1,4 2", "20240205"},
+		{"20240112", "w 5 3", "20240202"},
+		{"20240126", "w 5 2", "20240209"},
+		{"20240125", "w 1,2,3 1", "20240129"},
+		{"20240101", "w 1 0", ""},
+		{"20240101", "w 1 53", ""},
+		{"20240101", "w 1 x", ""},
+		{"20240101", "w 1,2 2 3", ""},
+	}
+	check()
+	tbl = []nextDate{
+		{"20240101", "m 2tu", "20240213"},
+		{"20240101", "m -1fr", "20240223"},
+		{"20240101", "m -1fr 3,9", "20240329"},
+		{"20240101", "m 1mo,15", "20240205"},
+		{"20240101", "m 3we,-2su 5", "20240515"},
+		{"20240101", "m 5th", "20240229"},
+		{"20240101", "m 6mo", ""},
+		{"20240101", "m 0tu", ""},
+		{"20240101", "m -6tu", ""},
+		{"20240101", "m 2xx", ""},
+		{"20240101", "m tu", ""},
+	}
+	check()
+	tbl = []nextDate{
+		{"20240113", "FREQ=DAILY;INTERVAL=7", "20240127"},
+		{"20240101", "FREQ=DAILY;BYDAY=SA,SU", "20240127"},
+		{"20240101", "FREQ=WEEKLY", "20240129"},
+		{"20240101", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", "20240129"},
+		{"20240101", "RRULE:FREQ=MONTHLY;BYDAY=-1FR;BYMONTH=3,9", "20240329"},
+		{"20240101", "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", "20240131"},
+		{"20240110", "FREQ=MONTHLY;INTERVAL=2", "20240310"},
+		{"20240110", "FREQ=MONTHLY;INTERVAL=1200", "21240110"},
+		{"20240110", "FREQ=MONTHLY;INTERVAL=1201", ""},
+		{"20240110", "FREQ=MONTHLY;INTERVAL=1000000", ""},
+		{"20240110", "FREQ=MONTHLY;INTERVAL=1200;BYMONTH=3", ""},
+		{"20240101", "FREQ=YEARLY;BYMONTH=12;BYMONTHDAY=25", "20241225"},
+		{"20230701", "FREQ=YEARLY", "20240701"},
+		{"20240101", "FREQ=DAILY;COUNT=3", ""},
+		{"20240101", "FREQ=DAILY;COUNT=26", ""},
+		{"20240101", "FREQ=DAILY;COUNT=27", "20240127"},
+		{"20240101", "FREQ=WEEKLY;COUNT=9;BYDAY=MO,FR", "20240129"},
+		{"20240101", "FREQ=WEEKLY;COUNT=8;BYDAY=MO,FR", ""},
+		{"20240101", "FREQ=DAILY;UNTIL=20240120", ""},
+		{"20240101", "FREQ=HOURLY;INTERVAL=5", "20240126"},
+		{"20240101", "FREQ=MINUTELY", ""},
+		{"20240101", "FREQ=WEEKLY;BYMONTHDAY=1", ""},
+		{"20240101", "FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13", ""},
+		{"20240101", "FREQ=DAILY;COUNT=2;UNTIL=20240301", ""},
+		{"20240101", "INTERVAL=2", ""},
+		{"20040126", "d 1", "20240127"},
+		{"20240101", "m 30 2", ""},
+		{"20240101", "m 31 2,4", ""},
+	}
+	check()
+	tbl = []nextDate{
+		{"20240125", "h 1", "20240126"},
+		{"20240125", "h 23", "20240126"},
+		{"20240120", "h 168", "20240127"},
+		{"20240125", "h 0", ""},
+		{"20240125", "h 169", ""},
+		{"20240125", "h", ""},
+	}
+	check()
+	tbl = []nextDate{
+		{"20240126", "b 1", "20240129"},
+		{"20240101", "b 5", "20240129"},
+		{"20240110", "b 3", "20240131"},
+		{"20240101", "b 0", ""},
+		{"20240101", "b 251", ""},
+		{"20240101", "b", ""},
+		{"20240101", "b 5 fwd", ""},
+		{"20240101", "m 27 fwd", "20240129"},
+		{"20240101", "m 27 back", "20240227"},
+		{"20240101", "m 26 fwd", "20240226"},
+		{"20240106", "d 7 fwd", "20240129"},
+		{"20240101", "w 6 back", "20240202"},
+		{"20230127", "y fwd", "20240129"},
+		{"20240101", "h 2 fwd", ""},
+		{"20240101", "d 7 up", ""},
+	}
+	check()
 }
@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ezfroze/go_final_project/pkg/db"
+	"github.com/ezfroze/go_final_project/pkg/server"
+	"github.com/stretchr/testify/assert"
+)
+
+type occurrences struct {
+	Occurrences []struct {
+		Date string         `json:"date"`
+		Task map[string]any `json:"task"`
+	} `json:"occurrences"`
+	Truncated bool   `json:"truncated"`
+	Error     string `json:"error"`
+}
+
+func getOccurrences(t *testing.T, from, to, search string) occurrences {
+	body, err := requestJSON(fmt.Sprintf("api/occurrences?from=%s&to=%s&search=%s",
+		from, to, url.QueryEscape(search)), nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var resp occurrences
+	err = json.Unmarshal(body, &resp)
+	assert.NoError(t, err)
+	return resp
+}
+
+func TestOccurrences(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	_, err := db.Exec(`DELETE FROM scheduler WHERE title LIKE '%#календарь%'`)
+	assert.NoError(t, err)
+
+	now := time.Now()
+	date := func(days int) string {
+		return now.AddDate(0, 0, days).Format(`20060102`)
+	}
+
+	resp := getOccurrences(t, "ooops", date(7), "")
+	assert.NotEmpty(t, resp.Error)
+	resp = getOccurrences(t, date(7), date(0), "")
+	assert.NotEmpty(t, resp.Error)
+
+	addTask(t, task{
+		date:   date(0),
+		title:  "Пробежка #календарь",
+		repeat: "d 2",
+	})
+	addTask(t, task{
+		date:  date(3),
+		title: "Врач #календарь",
+	})
+	m, err := postJSON("api/task", map[string]any{
+		"date":         date(1),
+		"title":        "Витамины #календарь",
+		"repeat":       "d 1",
+		"repeat_count": 2,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, m["id"])
+
+	resp = getOccurrences(t, date(1), date(6), "#календарь")
+	assert.Empty(t, resp.Error)
+	assert.False(t, resp.Truncated)
+
+	got := make([]string, 0, len(resp.Occurrences))
+	for _, o := range resp.Occurrences {
+		got = append(got, fmt.Sprint(o.Date, " ", o.Task["title"]))
+	}
+	assert.Equal(t, []string{
+		date(1) + " Витамины #календарь",
+		date(2) + " Пробежка #календарь",
+		date(2) + " Витамины #календарь",
+		date(3) + " Врач #календарь",
+		date(4) + " Пробежка #календарь",
+		date(6) + " Пробежка #календарь",
+	}, got)
+}
+
+func TestOccurrencesManyTasks(t *testing.T) {
+	now := time.Date(2024, 1, 26, 12, 0, 0, 0, time.UTC)
+	store := db.NewMemoryStore()
+	add := func(n int, task db.Task) {
+		for i := 0; i < n; i++ {
+			_, err := store.AddTask(&task)
+			assert.NoError(t, err)
+		}
+	}
+	// прошедшие задачи без повторения и задачи после диапазона повторений в нём не дают
+	add(1000, db.Task{Date: "20240110", Title: "Прошла"})
+	add(1000, db.Task{Date: "20240301", Title: "Потом"})
+	add(1, db.Task{Date: "20240205", Title: "Отчёт"})
+	ts := newTestServer(t, server.Config{Timezone: "UTC"}, store, fixedClock(now))
+
+	get := func() occurrences {
+		code, body := serverDo(t, http.MethodGet, ts.URL+"/api/occurrences?from=20240201&to=20240229", "", nil)
+		assert.Equal(t, http.StatusOK, code, body)
+		var resp occurrences
+		assert.NoError(t, json.Unmarshal([]byte(body), &resp), body)
+		return resp
+	}
+
+	resp := get()
+	assert.False(t, resp.Truncated)
+	if assert.Len(t, resp.Occurrences, 1) {
+		assert.Equal(t, "Отчёт", resp.Occurrences[0].Task["title"])
+	}
+
+	// задачи сверх лимита не раскрываются, и ответ помечается неполным
+	add(1000, db.Task{Date: "20240101", Title: "Закончилась", Repeat: "d 1", RepeatUntil: "20240120"})
+	resp = get()
+	assert.True(t, resp.Truncated)
+	assert.Empty(t, resp.Occurrences)
+}
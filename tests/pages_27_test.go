@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type tasksPage struct {
+	Tasks      []map[string]any `json:"tasks"`
+	NextCursor string           `json:"next_cursor"`
+	Total      int              `json:"total"`
+}
+
+func getTasksPage(t *testing.T, query url.Values) tasksPage {
+	body, err := requestJSON("api/tasks?"+query.Encode(), nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var page tasksPage
+	assert.NoError(t, json.Unmarshal(body, &page), string(body))
+	return page
+}
+
+func TestTasksPages(t *testing.T) {
+	date := time.Now().AddDate(0, 0, 50)
+	ids := make([]string, 0, 7)
+	for i := 0; i < 7; i++ {
+		ids = append(ids, addTask(t, task{
+			date:  date.Format(`20060102`),
+			title: fmt.Sprintf("Страница %d", i),
+		}))
+	}
+
+	if Search {
+		query := url.Values{"search": {date.Format(`02.01.2006`)}, "limit": {"3"}}
+		var got []string
+		for pages := 0; pages < 5; pages++ {
+			page := getTasksPage(t, query)
+			assert.Equal(t, 7, page.Total)
+			assert.LessOrEqual(t, len(page.Tasks), 3)
+			for _, task := range page.Tasks {
+				got = append(got, task["id"].(string))
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			query.Set("cursor", page.NextCursor)
+		}
+		assert.Equal(t, ids, got)
+	}
+
+	// по страницам можно пройти весь список, даже если задач больше размера страницы
+	query := url.Values{"limit": {"2"}}
+	seen := make(map[string]bool)
+	total := 0
+	for {
+		page := getTasksPage(t, query)
+		total = page.Total
+		for _, task := range page.Tasks {
+			id := task["id"].(string)
+			assert.False(t, seen[id], "задача %s на двух страницах", id)
+			seen[id] = true
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Set("cursor", page.NextCursor)
+	}
+	assert.Equal(t, total, len(seen))
+	for _, id := range ids {
+		assert.True(t, seen[id], id)
+	}
+
+	for _, query := range []string{"limit=0", "limit=501", "limit=abc", "cursor=abc", "cursor=e30"} {
+		body, err := requestJSON("api/tasks?"+query, nil, http.MethodGet)
+		assert.NoError(t, err)
+		var m map[string]any
+		assert.NoError(t, json.Unmarshal(body, &m))
+		if query == "cursor=e30" {
+			// {} - курсор без полей, страница с начала списка
+			assert.Nil(t, m["error"], query)
+			continue
+		}
+		assert.NotEmpty(t, m["error"], query)
+	}
+
+	for _, id := range ids {
+		_, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+	}
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ezfroze/go_final_project/pkg/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func nextDates(t *testing.T, now, date, clock, repeat, count string) map[string]any {
+	body, err := getBody(fmt.Sprintf("api/nextdate?now=%s&date=%s&time=%s&repeat=%s&count=%s",
+		url.QueryEscape(now), date, clock, url.QueryEscape(repeat), count))
+	assert.NoError(t, err)
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m), string(body))
+	return m
+}
+
+func TestNextDates(t *testing.T) {
+	tbl := []struct {
+		now, date, clock, repeat string
+		count                    int
+		want                     []string
+	}{
+		{"20240126", "20240101", "", "w 1,4", 3, []string{"20240129", "20240201", "20240205"}},
+		{"20240126", "20240101", "08:00", "m 1,-1", 4, []string{"20240131 08:00", "20240201 08:00", "20240229 08:00", "20240301 08:00"}},
+		{"20240126 12:00", "20240126", "09:30", "h 4", 3, []string{"20240126 13:30", "20240126 17:30", "20240126 21:30"}},
+		{"20240102", "20240101", "", "FREQ=DAILY;COUNT=5", 10, []string{"20240103", "20240104", "20240105"}},
+		{"20240115", "20240101", "", "FREQ=WEEKLY;UNTIL=20240201", 10, []string{"20240122", "20240129"}},
+		{"20240101", "20240229", "", "y", 2, []string{"20250301", "20260301"}},
+	}
+	for _, v := range tbl {
+		m := nextDates(t, v.now, v.date, v.clock, v.repeat, fmt.Sprint(v.count))
+		want := make([]any, 0, len(v.want))
+		for _, date := range v.want {
+			want = append(want, date)
+		}
+		assert.Equal(t, want, m["dates"], "%v", v)
+	}
+
+	for _, v := range []struct{ repeat, count string }{
+		{"m 30 2", "5"},
+		{"FREQ=DAILY;COUNT=3", "5"},
+		{"FREQ=DAILY;COUNT=1", "5"},
+		{"ooops", "5"},
+		{"d 1", "0"},
+		{"d 1", "101"},
+		{"d 1", "many"},
+	} {
+		m := nextDates(t, "20240126", "20240101", "", v.repeat, v.count)
+		assert.NotEmpty(t, m["error"], "%v", v)
+	}
+}
+
+func TestNextDatesMatchesNextDate(t *testing.T) {
+	now := time.Date(2024, 1, 26, 0, 0, 0, 0, time.UTC)
+	for _, repeat := range []string{"d 3", "w 2,6 2", "m -1fr 3,9", "b 4", "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1"} {
+		dates, err := api.NextDates(now, "20231201", "", repeat, 10, nil)
+		if !assert.NoError(t, err, repeat) {
+			continue
+		}
+		assert.Len(t, dates, 10, repeat)
+
+		// каждая следующая дата - это NextDate от предыдущей
+		cursor := now
+		for _, date := range dates {
+			next, err := api.NextDate(cursor, "20231201", repeat)
+			assert.NoError(t, err, repeat)
+			assert.Equal(t, next, date, repeat)
+			cursor, err = time.Parse(`20060102`, date)
+			assert.NoError(t, err)
+		}
+	}
+}
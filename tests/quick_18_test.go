@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ezfroze/go_final_project/pkg/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQuickTask(t *testing.T) {
+	// среда
+	now := time.Date(2024, 1, 24, 10, 15, 0, 0, time.UTC)
+
+	tbl := []struct {
+		text, title, date, time, repeat string
+	}{
+		{"pay rent every month on the 5th starting next Monday", "pay rent", "20240205", "", "m 5"},
+		{"звонок маме каждую среду", "звонок маме", "20240124", "", "w 3"},
+		{"Позвонить в банк завтра в 9:30", "Позвонить в банк", "20240125", "09:30", ""},
+		{"standup every weekday at 10:00", "standup", "20240124", "10:00", "w 1,2,3,4,5"},
+		{"отчёт каждые 5 рабочих дней с 01.02.2024", "отчёт", "20240201", "", "b 5"},
+		{"water plants every 3 days", "water plants", "20240124", "", "d 3"},
+		{"renew passport every year on 15.03.2024", "renew passport", "20240315", "", "y"},
+		{"stretch every 2 hours", "stretch", "20240124", "11:00", "h 2"},
+		{"оплатить интернет 10 числа каждого месяца", "оплатить интернет", "20240210", "", "m 10"},
+		{"team sync every other week on tue and thu", "team sync", "20240125", "", "w 2,4 2"},
+		{"уборка по субботам", "уборка", "20240127", "", "w 6"},
+		{"meeting on next friday", "meeting", "20240126", "", ""},
+		{"бег через 3 дня", "бег", "20240127", "", ""},
+		{"каждые 2 месяца платить налог", "платить налог", "20240124", "", "FREQ=MONTHLY;INTERVAL=2"},
+		{"купить хлеб", "купить хлеб", "20240124", "", ""},
+	}
+	for _, v := range tbl {
+		task, err := api.ParseQuickTask(v.text, now)
+		if !assert.NoError(t, err, v.text) {
+			continue
+		}
+		assert.Equal(t, v.title, task.Title, v.text)
+		assert.Equal(t, v.date, task.Date, v.text)
+		assert.Equal(t, v.time, task.Time, v.text)
+		assert.Equal(t, v.repeat, task.Repeat, v.text)
+	}
+
+	_, err := api.ParseQuickTask("every day", now)
+	assert.Error(t, err)
+	_, err = api.ParseQuickTask("отпуск 31.02.2024", now)
+	assert.Error(t, err)
+}
+
+func TestQuickTask(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	body, err := requestJSON("api/task/quick", map[string]any{"text": "звонок маме каждую среду в 19:00"}, http.MethodPost)
+	assert.NoError(t, err)
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.NotEmpty(t, m["id"])
+	assert.Equal(t, "w 3", m["repeat"])
+	assert.Equal(t, "по средам", m["repeat_description"])
+
+	var stored Task
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, fmt.Sprint(m["id"]))
+	assert.NoError(t, err)
+	assert.Equal(t, "звонок маме", stored.Title)
+	assert.Equal(t, "19:00", stored.Time)
+	assert.Equal(t, time.Wednesday, func() time.Weekday {
+		date, _ := time.Parse(`20060102`, stored.Date)
+		return date.Weekday()
+	}())
+	assert.GreaterOrEqual(t, stored.Date, time.Now().Format(`20060102`))
+
+	for _, text := range []string{"", "каждый день", "прочитать книгу каждые 500 дней",
+		"платить налог каждые 1000000 месяцев"} {
+		m, err = postJSON("api/task/quick", map[string]any{"text": text}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, m["error"], text)
+	}
+}
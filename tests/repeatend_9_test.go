@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepeatEnd(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+
+	tbl := []map[string]any{
+		{"title": "Без правила", "repeat_count": 3},
+		{"title": "Отрицательное количество", "repeat": "d 1", "repeat_count": -1},
+		{"title": "Неверная дата", "repeat": "d 1", "repeat_until": "2024.01.01"},
+		{"title": "Раньше даты задачи", "date": now.Format(`20060102`), "repeat": "d 1",
+			"repeat_until": now.AddDate(0, 0, -1).Format(`20060102`)},
+	}
+	for _, v := range tbl {
+		m, err := postJSON("api/task", v, http.MethodPost)
+		assert.NoError(t, err)
+		e, ok := m["error"]
+		assert.False(t, !ok || len(fmt.Sprint(e)) == 0, "Ожидается ошибка для задачи %v", v)
+	}
+
+	m, err := postJSON("api/task", map[string]any{
+		"title":        "Две тренировки",
+		"repeat":       "d 2",
+		"repeat_count": 2,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(m["id"])
+
+	ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	var stored Task
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 2).Format(`20060102`), stored.Date)
+	assert.Equal(t, 1, stored.RepeatCount)
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+
+	m, err = postJSON("api/task", map[string]any{
+		"title":        "До послезавтра",
+		"repeat":       "d 1",
+		"repeat_until": now.AddDate(0, 0, 2).Format(`20060102`),
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id = fmt.Sprint(m["id"])
+
+	for i := 1; i <= 2; i++ {
+		ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+
+		err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+		assert.NoError(t, err)
+		assert.Equal(t, now.AddDate(0, 0, i).Format(`20060102`), stored.Date)
+	}
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+
+	id = addTask(t, task{
+		title:  "Пять раз",
+		repeat: "FREQ=DAILY;COUNT=5",
+	})
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, stored.RepeatCount)
+}
+
+// Поля, которых нет в запросе на изменение, сохраняются
+func TestEditTaskKeepsFields(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	date := time.Now().AddDate(0, 0, 3).Format(`20060102`)
+	m, err := postJSON("api/task", map[string]any{
+		"date":         date,
+		"time":         "08:15",
+		"duration":     45,
+		"title":        "Бассейн",
+		"repeat":       "d 2",
+		"repeat_count": 4,
+		"repeat_until": time.Now().AddDate(0, 1, 0).Format(`20060102`),
+		"repeat_from":  "done",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(m["id"])
+
+	var before Task
+	assert.NoError(t, db.Get(&before, `SELECT * FROM scheduler WHERE id=?`, id))
+
+	// так задачу изменяет веб-интерфейс
+	ret, err := postJSON("api/task", map[string]any{
+		"id":      id,
+		"date":    date,
+		"title":   "Бассейн с тренером",
+		"comment": "взять шапочку",
+		"repeat":  "d 3",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+
+	var after Task
+	assert.NoError(t, db.Get(&after, `SELECT * FROM scheduler WHERE id=?`, id))
+	want := before
+	want.Title, want.Comment, want.Repeat = "Бассейн с тренером", "взять шапочку", "d 3"
+	assert.Equal(t, want, after)
+
+	// явно переданные поля заменяются, в том числе пустыми значениями
+	ret, err = postJSON("api/task", map[string]any{
+		"id":           id,
+		"title":        "Бассейн",
+		"time":         "",
+		"repeat_count": 0,
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+
+	assert.NoError(t, db.Get(&after, `SELECT * FROM scheduler WHERE id=?`, id))
+	assert.Equal(t, date, after.Date)
+	assert.Equal(t, "", after.Time)
+	assert.Equal(t, 0, after.RepeatCount)
+	assert.Equal(t, "взять шапочку", after.Comment)
+	assert.Equal(t, before.RepeatUntil, after.RepeatUntil)
+
+	ret, err = postJSON("api/task", map[string]any{"id": "7645346343", "title": "Нет такой"}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	_, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+}
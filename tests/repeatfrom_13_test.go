@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepeatFromDone(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+
+	tbl := []map[string]any{
+		{"title": "Без правила", "repeat_from": "done"},
+		{"title": "Неизвестная точка отсчёта", "repeat": "d 7", "repeat_from": "ooops"},
+	}
+	for _, v := range tbl {
+		m, err := postJSON("api/task", v, http.MethodPost)
+		assert.NoError(t, err)
+		e, ok := m["error"]
+		assert.False(t, !ok || len(fmt.Sprint(e)) == 0, "Ожидается ошибка для задачи %v", v)
+	}
+
+	check := func(repeatFrom string, want string) {
+		m, err := postJSON("api/task", map[string]any{
+			"date":        now.AddDate(0, 0, 3).Format(`20060102`),
+			"title":       "Полить цветы",
+			"repeat":      "d 7",
+			"repeat_from": repeatFrom,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		id := fmt.Sprint(m["id"])
+
+		ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+
+		var stored Task
+		err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+		assert.NoError(t, err)
+		assert.Equal(t, want, stored.Date)
+		assert.Equal(t, repeatFrom, stored.RepeatFrom)
+	}
+
+	check("", now.AddDate(0, 0, 10).Format(`20060102`))
+	check("done", now.AddDate(0, 0, 7).Format(`20060102`))
+}
@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepeatConvert(t *testing.T) {
+	tbl := []struct {
+		repeat string
+		legacy string
+		rrule  string
+	}{
+		{"d 7", "d 7", "FREQ=DAILY;INTERVAL=7"},
+		{"y", "y", "FREQ=YEARLY"},
+		{"w 1,4 2", "w 1,4 2", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH"},
+		{"m 1,-1 2,8", "m 1,-1 2,8", "FREQ=MONTHLY;BYMONTHDAY=1,-1;BYMONTH=2,8"},
+		{"m -1fr 3,9", "m -1fr 3,9", "FREQ=MONTHLY;BYDAY=-1FR;BYMONTH=3,9"},
+		{"m 2tu,15", "m 15,2tu", ""},
+		{"RRULE:FREQ=WEEKLY;BYDAY=MO,WE", "w 1,3", "FREQ=WEEKLY;BYDAY=MO,WE"},
+		{"FREQ=DAILY;COUNT=5", "", "FREQ=DAILY;COUNT=5"},
+		{"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", "", "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1"},
+		{"FREQ=YEARLY;BYMONTH=11;BYDAY=4TH", "m 4th 11", "FREQ=MONTHLY;BYDAY=4TH;BYMONTH=11"},
+		{"FREQ=YEARLY;INTERVAL=2", "y 2", "FREQ=YEARLY;INTERVAL=2"},
+		{"FREQ=YEARLY;INTERVAL=3;BYMONTH=3,9;BYMONTHDAY=1,15", "y 01.03,15.03,01.09,15.09 3", "FREQ=YEARLY;INTERVAL=3;BYMONTH=3,9;BYMONTHDAY=1,15"},
+		{"y 25.12,01.01", "y 25.12,01.01", ""},
+		{"y 29.02 4", "y 29.02 4", ""},
+	}
+
+	for _, v := range tbl {
+		body, err := requestJSON("api/repeat/convert?repeat="+url.QueryEscape(v.repeat), nil, http.MethodGet)
+		assert.NoError(t, err)
+
+		var m map[string]string
+		err = json.Unmarshal(body, &m)
+		assert.NoError(t, err)
+		assert.Equal(t, v.legacy, m["repeat"], v.repeat)
+		assert.Equal(t, v.rrule, m["rrule"], v.repeat)
+	}
+
+	m, err := postJSON("api/repeat/convert?repeat=ooops", nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, m["error"])
+}
+
+func TestAddTaskRRule(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	id := addTask(t, task{
+		date:   "20240101",
+		title:  "Планёрка",
+		repeat: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH",
+	})
+
+	var stored Task
+	err := db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, "w 1,4 2", stored.Repeat)
+
+	id = addTask(t, task{
+		title:  "Ежедневная зарядка",
+		repeat: "FREQ=DAILY;COUNT=10",
+	})
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, "FREQ=DAILY;COUNT=10", stored.Repeat)
+}
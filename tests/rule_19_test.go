@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ezfroze/go_final_project/pkg/api"
+	"github.com/ezfroze/go_final_project/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepeatRuleRoundTrip(t *testing.T) {
+	tbl := []struct {
+		repeat, canonical string
+	}{
+		{"d 7", "d 7"},
+		{"d 3 fwd", "d 3 fwd"},
+		{"y", "y"},
+		{"y back", "y back"},
+		{"h 4", "h 4"},
+		{"b 5", "b 5"},
+		{"w 7", "w 7"},
+		{"w 1,4 2", "w 1,4 2"},
+		{"m 1,-1 2,8", "m 1,-1 2,8"},
+		{"m -1fr 3,9", "m -1fr 3,9"},
+		{"m 2tu,15", "m 15,2tu"},
+		{"m 1 5 fwd", "m 1 5 fwd"},
+		{"RRULE:FREQ=WEEKLY;BYDAY=MO,WE", "w 1,3"},
+		{"FREQ=WEEKLY", "FREQ=WEEKLY"},
+		{"FREQ=DAILY;INTERVAL=2;COUNT=5", "FREQ=DAILY;INTERVAL=2;COUNT=5"},
+		{"FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=10", "FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=10"},
+		{"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1"},
+		{"FREQ=WEEKLY;BYDAY=SU;UNTIL=20240301", "FREQ=WEEKLY;BYDAY=SU;UNTIL=20240301"},
+	}
+	for _, v := range tbl {
+		rule, err := api.RuleFromRepeat(v.repeat)
+		if !assert.NoError(t, err, v.repeat) {
+			continue
+		}
+		repeat, err := api.RepeatFromRule(rule)
+		assert.NoError(t, err, v.repeat)
+		assert.Equal(t, v.canonical, repeat, v.repeat)
+
+		// JSON не теряет полей
+		data, err := json.Marshal(rule)
+		assert.NoError(t, err)
+		var decoded db.RepeatRule
+		assert.NoError(t, json.Unmarshal(data, &decoded))
+		again, err := api.RuleFromRepeat(repeat)
+		assert.NoError(t, err, v.repeat)
+		assert.Equal(t, again, &decoded, v.repeat)
+	}
+
+	rule, err := api.RuleFromRepeat("m -1fr,1 3,9 back")
+	assert.NoError(t, err)
+	data, err := json.Marshal(rule)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type":"m","interval":1,"days":[1],"months":[3,9],
+		"ordinals":[{"n":-1,"weekday":5}],"roll":"back"}`, string(data))
+
+	for _, rule := range []*db.RepeatRule{
+		nil,
+		{Type: "x"},
+		{Type: "d", Interval: 500},
+		{Type: "d", Days: []int{1}},
+		{Type: "w", Days: []int{8}},
+		{Type: "w", Months: []int{1}},
+		{Type: "m", Days: []int{40}},
+		{Type: "m", Ordinals: []db.RepeatOrdinal{{N: 1, Weekday: 0}}},
+		{Type: "y", Interval: 101},
+		{Type: "b", Interval: 1, End: &db.RepeatEnd{Count: 3}},
+		{Type: "h", Interval: 2, Roll: "fwd"},
+		{Type: "d", Interval: 1, Roll: "later"},
+	} {
+		_, err := api.RepeatFromRule(rule)
+		assert.Error(t, err, "%+v", rule)
+	}
+}
+
+func TestTaskRepeatRule(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	date := time.Now().Format(`20060102`)
+	m, err := postJSON("api/task", map[string]any{
+		"date":        date,
+		"title":       "Планёрка",
+		"repeat_rule": map[string]any{"type": "w", "interval": 2, "days": []int{1, 4}},
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, m["error"])
+	id := fmt.Sprint(m["id"])
+
+	var stored Task
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, "w 1,4 2", stored.Repeat)
+
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var ret struct {
+		Repeat     string          `json:"repeat"`
+		RepeatRule json.RawMessage `json:"repeat_rule"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &ret))
+	assert.Equal(t, "w 1,4 2", ret.Repeat)
+	assert.JSONEq(t, `{"type":"w","interval":2,"days":[1,4]}`, string(ret.RepeatRule))
+
+	// оба поля с одним правилом в разной записи
+	m, err = postJSON("api/task", map[string]any{
+		"id":          id,
+		"date":        stored.Date,
+		"title":       "Планёрка",
+		"repeat":      "FREQ=MONTHLY;BYDAY=-1FR",
+		"repeat_rule": map[string]any{"type": "m", "ordinals": []map[string]int{{"n": -1, "weekday": 5}}},
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, m["error"])
+
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, "m -1fr", stored.Repeat)
+
+	for _, v := range []map[string]any{
+		{"repeat": "d 2", "repeat_rule": map[string]any{"type": "d", "interval": 3}},
+		{"repeat_rule": map[string]any{"type": "m", "days": []int{40}}},
+		{"repeat_rule": map[string]any{"type": "q"}},
+	} {
+		v["date"] = date
+		v["title"] = "Ошибка"
+		m, err = postJSON("api/task", v, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, m["error"], "%v", v)
+	}
+}
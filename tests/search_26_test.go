@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func searchTasks(t *testing.T, search string) []map[string]any {
+	return getTasks(t, url.QueryEscape(search))
+}
+
+func searchTitles(tasks []map[string]any) []string {
+	titles := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		titles = append(titles, task["title"].(string))
+	}
+	return titles
+}
+
+func TestSearchFullText(t *testing.T) {
+	if !Search {
+		return
+	}
+
+	db := openDB(t)
+	defer db.Close()
+
+	date := time.Now().AddDate(0, 0, 3).Format(`20060102`)
+	first := addTask(t, task{date: date, title: "Купить ЗЕБРОИДА", comment: "в зоопарке на Садовой"})
+	second := addTask(t, task{date: date, title: "Отчёт", comment: "про зеброидов и их корм, зеброид любит морковь"})
+
+	// регистр кириллицы не важен, совпадение в заголовке выше совпадения в комментарии
+	tasks := searchTasks(t, "зеброид")
+	assert.Equal(t, []string{"Купить ЗЕБРОИДА", "Отчёт"}, searchTitles(tasks))
+	if assert.Len(t, tasks, 2) {
+		assert.Equal(t, map[string]any{"title": "Купить <mark>ЗЕБРОИДА</mark>"}, tasks[0]["highlight"])
+		assert.Equal(t, map[string]any{
+			"title":   "Отчёт",
+			"comment": "про <mark>зеброидов</mark> и их корм, <mark>зеброид</mark> любит морковь",
+		}, tasks[1]["highlight"])
+	}
+
+	assert.Equal(t, []string{"Отчёт"}, searchTitles(searchTasks(t, `"зеброид любит"`)))
+	assert.Equal(t, []string{"Купить ЗЕБРОИДА"}, searchTitles(searchTasks(t, "зеброид садов")))
+	assert.Empty(t, searchTasks(t, `"зеброид садовой"`))
+
+	// индекс следует за изменениями задачи
+	_, err := postJSON("api/task", map[string]any{
+		"id": first, "date": date, "title": "Купить жирафа", "comment": "", "repeat": "",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Отчёт"}, searchTitles(searchTasks(t, "зеброид")))
+	assert.Equal(t, []string{"Купить жирафа"}, searchTitles(searchTasks(t, "ЖИРАФ")))
+
+	_, err = db.Exec(`INSERT INTO scheduler (date, title, comment, repeat) VALUES (?, 'Жирафёнок', '', '')`, date)
+	assert.NoError(t, err)
+	// задача, добавленная мимо API, тоже в индексе. Короткий заголовок выше в выдаче
+	assert.Equal(t, []string{"Жирафёнок", "Купить жирафа"}, searchTitles(searchTasks(t, "жираф")))
+
+	for _, id := range []string{first, second} {
+		_, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+	}
+	_, err = db.Exec(`DELETE FROM scheduler WHERE title = 'Жирафёнок'`)
+	assert.NoError(t, err)
+	assert.Empty(t, searchTasks(t, "зеброид"))
+	assert.Empty(t, searchTasks(t, "жираф"))
+}
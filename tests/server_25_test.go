@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ezfroze/go_final_project/pkg/db"
+	"github.com/ezfroze/go_final_project/pkg/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(t *testing.T, cfg server.Config, store db.Store, clock func() time.Time) *httptest.Server {
+	srv, err := server.New(cfg, store, clock)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func fixedClock(now time.Time) func() time.Time {
+	return func() time.Time { return now }
+}
+
+func serverDo(t *testing.T, method, url, body string, cookie *http.Cookie) (int, string) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	assert.NoError(t, err)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return 0, ""
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return resp.StatusCode, strings.TrimSpace(string(data))
+}
+
+func serverAddTask(t *testing.T, url string, task map[string]any) string {
+	data, err := json.Marshal(task)
+	assert.NoError(t, err)
+	resp, err := http.Post(url+"/api/task", "application/json", bytes.NewReader(data))
+	if !assert.NoError(t, err) {
+		return ""
+	}
+	defer resp.Body.Close()
+
+	var m map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&m))
+	id, _ := m["id"].(string)
+	assert.NotEmpty(t, id, "%v", m)
+	return id
+}
+
+func TestServerInstances(t *testing.T) {
+	now := time.Date(2024, 1, 26, 12, 0, 0, 0, time.UTC)
+	first := newTestServer(t, server.Config{Timezone: "UTC"}, db.NewMemoryStore(), fixedClock(now))
+	second := newTestServer(t, server.Config{Timezone: "UTC"}, db.NewMemoryStore(), fixedClock(now))
+
+	serverAddTask(t, first.URL, map[string]any{"title": "Только в первом"})
+
+	for _, v := range []struct {
+		url  string
+		want int
+	}{{first.URL, 1}, {second.URL, 0}} {
+		code, body := serverDo(t, http.MethodGet, v.url+"/api/tasks", "", nil)
+		assert.Equal(t, http.StatusOK, code)
+		var resp struct {
+			Tasks []map[string]any `json:"tasks"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(body), &resp))
+		assert.Len(t, resp.Tasks, v.want, v.url)
+	}
+}
+
+func TestServerClock(t *testing.T) {
+	// 26.01.2024 22:30 UTC - это уже 27.01.2024 в Москве
+	now := time.Date(2024, 1, 26, 22, 30, 0, 0, time.UTC)
+
+	tbl := []struct {
+		timezone string
+		today    string
+		next     string
+	}{
+		{"UTC", "20240126", "20240127"},
+		{"Europe/Moscow", "20240127", "20240128"},
+	}
+	for _, v := range tbl {
+		store := db.NewMemoryStore()
+		ts := newTestServer(t, server.Config{Timezone: v.timezone}, store, fixedClock(now))
+
+		// без now дата считается по часам сервера
+		code, body := serverDo(t, http.MethodGet, ts.URL+"/api/nextdate?date=20240105&repeat=d%201", "", nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, v.next, body, v.timezone)
+
+		id := serverAddTask(t, ts.URL, map[string]any{"title": "Без даты"})
+		task, err := store.GetTask(id)
+		if assert.NoError(t, err) {
+			assert.Equal(t, v.today, task.Date, v.timezone)
+		}
+	}
+
+	_, err := server.New(server.Config{Timezone: "Mars/Olympus"}, db.NewMemoryStore(), nil)
+	assert.Error(t, err)
+}
+
+func TestServerHolidays(t *testing.T) {
+	now := time.Date(2024, 1, 26, 12, 0, 0, 0, time.UTC)
+	store := db.NewMemoryStore()
+	assert.NoError(t, store.AddHolidays([]db.Holiday{{Date: "20240129", Title: "Выходной"}}))
+
+	// календарь загружается из хранилища своего сервера
+	withHoliday := newTestServer(t, server.Config{Timezone: "UTC"}, store, fixedClock(now))
+	without := newTestServer(t, server.Config{Timezone: "UTC"}, db.NewMemoryStore(), fixedClock(now))
+
+	_, body := serverDo(t, http.MethodGet, withHoliday.URL+"/api/nextdate?date=20240126&repeat=b%201", "", nil)
+	assert.Equal(t, "20240130", body)
+	_, body = serverDo(t, http.MethodGet, without.URL+"/api/nextdate?date=20240126&repeat=b%201", "", nil)
+	assert.Equal(t, "20240129", body)
+}
+
+func TestServerPassword(t *testing.T) {
+	open := newTestServer(t, server.Config{}, db.NewMemoryStore(), nil)
+	closed := newTestServer(t, server.Config{Password: "secret"}, db.NewMemoryStore(), nil)
+
+	code, _ := serverDo(t, http.MethodGet, open.URL+"/api/tasks", "", nil)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = serverDo(t, http.MethodGet, closed.URL+"/api/tasks", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, _ = serverDo(t, http.MethodPost, closed.URL+"/api/signin", `{"password":"wrong"}`, nil)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, body := serverDo(t, http.MethodPost, closed.URL+"/api/signin", `{"password":"secret"}`, nil)
+	assert.Equal(t, http.StatusOK, code)
+	var m map[string]string
+	assert.NoError(t, json.Unmarshal([]byte(body), &m))
+	assert.NotEmpty(t, m["token"])
+
+	code, _ = serverDo(t, http.MethodGet, closed.URL+"/api/tasks", "", &http.Cookie{Name: "token", Value: m["token"]})
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _ = serverDo(t, http.MethodGet, closed.URL+"/api/tasks", "", &http.Cookie{Name: "token", Value: "ooops"})
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
@@ -1,6 +1,5 @@
 package tests
 
-var Port = 7540
 var DBFile = "../scheduler.db"
 var FullNextDate = true
 var Search = true
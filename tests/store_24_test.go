@@ -0,0 +1,633 @@
+package tests
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ezfroze/go_final_project/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, func(t *testing.T) db.Store {
+		return db.NewMemoryStore()
+	})
+}
+
+func TestSQLiteStore(t *testing.T) {
+	testStore(t, func(t *testing.T) db.Store {
+		database, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "scheduler.db"))
+		assert.NoError(t, err)
+		t.Cleanup(func() { database.Close() })
+		assert.NoError(t, db.Migrate(database))
+		return db.NewSQLiteStore(database)
+	})
+}
+
+// testStore - общие тесты для всех реализаций db.Store
+func testStore(t *testing.T, newStore func(t *testing.T) db.Store) {
+	now := time.Date(2024, 1, 26, 12, 0, 0, 0, time.UTC)
+
+	add := func(t *testing.T, store db.Store, task db.Task) string {
+		id, err := store.AddTask(&task)
+		assert.NoError(t, err)
+		return strconv.FormatInt(id, 10)
+	}
+	titles := func(tasks []*db.Task) []string {
+		result := make([]string, 0, len(tasks))
+		for _, task := range tasks {
+			result = append(result, task.Title)
+		}
+		return result
+	}
+
+	t.Run("AddGet", func(t *testing.T) {
+		store := newStore(t)
+		want := db.Task{
+			Date: "20240126", Time: "09:30", Duration: 45, Title: "Созвон", Comment: "Планы",
+			Repeat: "d 7", RepeatUntil: "20241231", RepeatCount: 5, RepeatFrom: "done", RepeatAnchor: "20240127",
+			RepeatDescription: "каждые 7 дней", RepeatRule: &db.RepeatRule{Type: "d", Interval: 7},
+		}
+		id := add(t, store, want)
+
+		got, err := store.GetTask(id)
+		if !assert.NoError(t, err) {
+			return
+		}
+		want.ID = id
+		want.RepeatDescription = ""
+		want.RepeatRule = nil
+		assert.Equal(t, &want, got)
+
+		// изменение полученной задачи не меняет хранилище
+		got.Title = "Другое"
+		again, err := store.GetTask(id)
+		assert.NoError(t, err)
+		assert.Equal(t, "Созвон", again.Title)
+
+		again, err = store.GetTask("0" + id)
+		assert.NoError(t, err)
+		assert.Equal(t, id, again.ID)
+
+		for _, missing := range []string{"999", "abc", ""} {
+			_, err = store.GetTask(missing)
+			assert.True(t, errors.Is(err, sql.ErrNoRows), missing)
+		}
+	})
+
+	t.Run("IDs", func(t *testing.T) {
+		store := newStore(t)
+		first := add(t, store, db.Task{Date: "20240126", Title: "Первая"})
+		second := add(t, store, db.Task{Date: "20240126", Title: "Вторая"})
+		assert.Equal(t, "1", first)
+		assert.Equal(t, "2", second)
+
+		// id удалённой задачи не используется повторно
+		assert.NoError(t, store.DeleteTask(second, now))
+		assert.NoError(t, store.PurgeTask(second))
+		assert.Equal(t, "3", add(t, store, db.Task{Date: "20240126", Title: "Третья"}))
+	})
+
+	t.Run("TasksOrder", func(t *testing.T) {
+		store := newStore(t)
+		tasks, err := store.Tasks(10, "", now)
+		assert.NoError(t, err)
+		assert.NotNil(t, tasks)
+		assert.Empty(t, tasks)
+
+		add(t, store, db.Task{Date: "20240201", Title: "d"})
+		add(t, store, db.Task{Date: "20240126", Time: "18:00", Title: "c"})
+		add(t, store, db.Task{Date: "20240126", Title: "a"})
+		add(t, store, db.Task{Date: "20240126", Time: "09:00", Title: "b"})
+		add(t, store, db.Task{Date: "20240126", Title: "a2"})
+
+		tasks, err = store.Tasks(10, "", now)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a", "a2", "b", "c", "d"}, titles(tasks))
+
+		tasks, err = store.Tasks(2, "", now)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a", "a2"}, titles(tasks))
+	})
+
+	t.Run("TasksPage", func(t *testing.T) {
+		store := newStore(t)
+		add(t, store, db.Task{Date: "20240201", Title: "d"})
+		add(t, store, db.Task{Date: "20240126", Time: "18:00", Title: "c"})
+		add(t, store, db.Task{Date: "20240126", Title: "a"})
+		add(t, store, db.Task{Date: "20240126", Time: "09:00", Title: "b"})
+		add(t, store, db.Task{Date: "20240126", Title: "a2"})
+
+		page, err := store.TasksPage(2, db.TaskQuery{Now: now}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a", "a2"}, titles(page.Tasks))
+		assert.Equal(t, 5, page.Total)
+		if !assert.NotNil(t, page.Next) {
+			return
+		}
+
+		// задача перед курсором не сдвигает следующие страницы
+		add(t, store, db.Task{Date: "20240101", Title: "раньше"})
+
+		page, err = store.TasksPage(2, db.TaskQuery{Now: now}, page.Next)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"b", "c"}, titles(page.Tasks))
+		assert.Equal(t, 6, page.Total)
+		if !assert.NotNil(t, page.Next) {
+			return
+		}
+
+		page, err = store.TasksPage(2, db.TaskQuery{Now: now}, page.Next)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"d"}, titles(page.Tasks))
+		assert.Nil(t, page.Next)
+
+		// последняя полная страница тоже не имеет следующей
+		page, err = store.TasksPage(6, db.TaskQuery{Now: now}, nil)
+		assert.NoError(t, err)
+		assert.Len(t, page.Tasks, 6)
+		assert.Nil(t, page.Next)
+
+		page, err = store.TasksPage(2, db.TaskQuery{Search: "26.01.2024", Now: now}, &db.TaskCursor{Date: "20240126", Time: "", ID: 3})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a2", "b"}, titles(page.Tasks))
+		assert.Equal(t, 4, page.Total)
+
+		_, err = store.TasksPage(0, db.TaskQuery{Now: now}, nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("TasksPageSearch", func(t *testing.T) {
+		store := newStore(t)
+		add(t, store, db.Task{Date: "20240126", Title: "Хлеб"})
+		add(t, store, db.Task{Date: "20240125", Title: "Купить хлеб"})
+		add(t, store, db.Task{Date: "20240127", Title: "Купить хлеб и молоко"})
+		add(t, store, db.Task{Date: "20240124", Title: "Молоко"})
+
+		var all []string
+		var after *db.TaskCursor
+		for {
+			page, err := store.TasksPage(2, db.TaskQuery{Search: "хлеб", Now: now}, after)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, 3, page.Total)
+			all = append(all, titles(page.Tasks)...)
+			if page.Next == nil {
+				break
+			}
+			after = page.Next
+		}
+		assert.Equal(t, []string{"Хлеб", "Купить хлеб", "Купить хлеб и молоко"}, all)
+
+		page, err := store.TasksPage(2, db.TaskQuery{Search: "нет такого", Now: now}, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, page.Tasks)
+		assert.Equal(t, 0, page.Total)
+		assert.Nil(t, page.Next)
+	})
+
+	t.Run("TasksSearch", func(t *testing.T) {
+		store := newStore(t)
+		add(t, store, db.Task{Date: "20240125", Title: "Team meeting", Comment: ""})
+		add(t, store, db.Task{Date: "20240126", Title: "Оплатить УК", Comment: "до 25 числа"})
+		add(t, store, db.Task{Date: "20240127", Title: "Бег", Comment: "Парк abc"})
+		add(t, store, db.Task{Date: "20240126", Title: "Звонок", Comment: "100%"})
+
+		tbl := []struct {
+			search string
+			want   []string
+		}{
+			{"MEET", []string{"Team meeting"}},
+			{"УК", []string{"Оплатить УК"}},
+			{"ук", []string{"Оплатить УК"}},
+			{"ОПЛАТ", []string{"Оплатить УК"}},
+			{"числа", []string{"Оплатить УК"}},
+			{"исла", []string{}},
+			{"abc", []string{"Бег"}},
+			{"100%", []string{"Звонок"}},
+			{"%", []string{}},
+			{"оплатить 25", []string{"Оплатить УК"}},
+			{"оплатить бег", []string{}},
+			{`"до 25"`, []string{"Оплатить УК"}},
+			{`"до 2"`, []string{}},
+			{`"до числа"`, []string{}},
+			{"26.01.2024", []string{"Оплатить УК", "Звонок"}},
+			{"сегодня", []string{"Оплатить УК", "Звонок"}},
+			{"tomorrow", []string{"Бег"}},
+			{"вчера", []string{"Team meeting"}},
+			{"нет такого", []string{}},
+		}
+		for _, v := range tbl {
+			tasks, err := store.Tasks(10, v.search, now)
+			assert.NoError(t, err, v.search)
+			assert.Equal(t, v.want, titles(tasks), v.search)
+		}
+	})
+
+	t.Run("TasksRank", func(t *testing.T) {
+		store := newStore(t)
+		add(t, store, db.Task{Date: "20240125", Title: "Купить молоко", Comment: "и хлеб"})
+		add(t, store, db.Task{Date: "20240126", Title: "Позвонить маме", Comment: "про хлеб и хлеб к ужину"})
+		add(t, store, db.Task{Date: "20240127", Title: "Хлеб", Comment: ""})
+		add(t, store, db.Task{Date: "20240128", Title: "Отчёт", Comment: "квартальный"})
+		add(t, store, db.Task{Date: "20240129", Title: "Спорт", Comment: "бег"})
+		add(t, store, db.Task{Date: "20240130", Title: "Уборка", Comment: "кухня"})
+
+		// совпадение в коротком заголовке весит больше, чем в длинном комментарии
+		tasks, err := store.Tasks(10, "хлеб", now)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Хлеб", "Позвонить маме", "Купить молоко"}, titles(tasks))
+
+		tasks, err = store.Tasks(2, "хлеб", now)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Хлеб", "Позвонить маме"}, titles(tasks))
+	})
+
+	t.Run("TasksHighlight", func(t *testing.T) {
+		store := newStore(t)
+		add(t, store, db.Task{Date: "20240126", Title: "Оплатить <УК>", Comment: "Квитанция за январь, оплатить до 25 числа через банк, потом отправить чек в чат дома"})
+		add(t, store, db.Task{Date: "20240127", Title: "Звонок", Comment: "Оплата"})
+
+		// короткий комментарий выше в выдаче
+		tasks, err := store.Tasks(10, "оплат", now)
+		assert.NoError(t, err)
+		if assert.Len(t, tasks, 2) {
+			assert.Equal(t, &db.TaskHighlight{Title: "Звонок", Comment: "<mark>Оплата</mark>"}, tasks[0].Highlight)
+			assert.Equal(t, &db.TaskHighlight{
+				Title:   "<mark>Оплатить</mark> &lt;УК&gt;",
+				Comment: "Квитанция за январь, <mark>оплатить</mark> до 25 числа через банк, потом отправить чек…",
+			}, tasks[1].Highlight)
+		}
+
+		tasks, err = store.Tasks(10, `"через банк" дома`, now)
+		assert.NoError(t, err)
+		if assert.Len(t, tasks, 1) {
+			assert.Equal(t, "…до 25 числа <mark>через банк</mark>, потом отправить чек в чат <mark>дома</mark>",
+				tasks[0].Highlight.Comment)
+		}
+
+		// без поиска по словам совпадений нет
+		tasks, err = store.Tasks(10, "", now)
+		assert.NoError(t, err)
+		assert.Nil(t, tasks[0].Highlight)
+		tasks, err = store.Tasks(10, "26.01.2024", now)
+		assert.NoError(t, err)
+		assert.Nil(t, tasks[0].Highlight)
+	})
+
+	t.Run("TasksFilter", func(t *testing.T) {
+		store := newStore(t)
+		add(t, store, db.Task{Date: "20231230", Title: "Счёт за декабрь", Comment: "оплатить"})
+		add(t, store, db.Task{Date: "20240126", Time: "09:00", Title: "Созвон", Repeat: "d 7"})
+		add(t, store, db.Task{Date: "20240126", Time: "18:00", Title: "Отчёт", Comment: "счёт на оплату"})
+		add(t, store, db.Task{Date: "20240201", Title: "Счёт за январь", Repeat: "m 1"})
+		add(t, store, db.Task{Date: "20240315", Title: "Отпуск"})
+
+		tbl := []struct {
+			filter string
+			want   []string
+		}{
+			{"date>=20240101 date<01.02.2024", []string{"Созвон", "Отчёт"}},
+			{"date:today", []string{"Созвон", "Отчёт"}},
+			{"date>today", []string{"Счёт за январь", "Отпуск"}},
+			{"date!=20240126", []string{"Счёт за декабрь", "Счёт за январь", "Отпуск"}},
+			{"repeat:yes", []string{"Созвон", "Счёт за январь"}},
+			{"repeat=no date<20240301", []string{"Счёт за декабрь", "Отчёт"}},
+			{"overdue:yes", []string{"Счёт за декабрь", "Созвон"}},
+			{"overdue:no repeat:no", []string{"Отчёт", "Отпуск"}},
+			{"счёт", []string{"Счёт за декабрь", "Отчёт", "Счёт за январь"}},
+			{"title:счёт", []string{"Счёт за декабрь", "Счёт за январь"}},
+			{`comment:"на оплату"`, []string{"Отчёт"}},
+			{`text:"счёт за"`, []string{"Счёт за декабрь", "Счёт за январь"}},
+			{"title:счёт AND repeat:yes", []string{"Счёт за январь"}},
+			{"title:счёт OR date:20240315", []string{"Счёт за декабрь", "Счёт за январь", "Отпуск"}},
+			{"NOT title:счёт", []string{"Созвон", "Отчёт", "Отпуск"}},
+			{"-title:счёт -repeat:yes", []string{"Отчёт", "Отпуск"}},
+			{"date>=20240101 (repeat:yes OR overdue:yes) -title:созвон", []string{"Счёт за январь"}},
+			{"отп", []string{"Отпуск"}},
+			{`"отп"`, nil},
+		}
+		for _, v := range tbl {
+			filter, err := db.ParseFilter(v.filter)
+			if !assert.NoError(t, err, v.filter) {
+				continue
+			}
+			page, err := store.TasksPage(10, db.TaskQuery{Filter: filter, Now: now}, nil)
+			assert.NoError(t, err, v.filter)
+			if v.want == nil {
+				v.want = []string{}
+			}
+			assert.Equal(t, v.want, titles(page.Tasks), v.filter)
+			assert.Equal(t, len(v.want), page.Total, v.filter)
+		}
+
+		// фильтр сочетается с поиском и страницами
+		filter, err := db.ParseFilter("repeat:no")
+		assert.NoError(t, err)
+		page, err := store.TasksPage(1, db.TaskQuery{Search: "счёт", Filter: filter, Now: now}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Счёт за декабрь"}, titles(page.Tasks))
+		assert.Equal(t, 2, page.Total)
+		page, err = store.TasksPage(1, db.TaskQuery{Search: "счёт", Filter: filter, Now: now}, page.Next)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Отчёт"}, titles(page.Tasks))
+		assert.Nil(t, page.Next)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		store := newStore(t)
+		id := add(t, store, db.Task{Date: "20240126", Title: "Старая", Repeat: "d 1"})
+
+		updated := db.Task{ID: id, Date: "20240130", Time: "10:00", Duration: 30, Title: "Новая",
+			Comment: "Комментарий", Repeat: "w 1", RepeatCount: 2, RepeatFrom: "date"}
+		assert.NoError(t, store.UpdateTask(&updated))
+
+		got, err := store.GetTask(id)
+		assert.NoError(t, err)
+		assert.Equal(t, &updated, got)
+
+		assert.Error(t, store.UpdateTask(&db.Task{Title: "Без id"}))
+		assert.Error(t, store.UpdateTask(&db.Task{ID: "999", Title: "Нет такой"}))
+		assert.Error(t, store.UpdateTask(&db.Task{ID: "abc", Title: "Нет такой"}))
+	})
+
+	t.Run("UpdateDate", func(t *testing.T) {
+		store := newStore(t)
+		id := add(t, store, db.Task{Date: "20240126", Time: "09:00", Title: "Задача", Repeat: "h 4"})
+
+		assert.NoError(t, store.UpdateTaskDate(id, "20240127", "13:00"))
+		got, err := store.GetTask(id)
+		assert.NoError(t, err)
+		assert.Equal(t, "20240127", got.Date)
+		assert.Equal(t, "13:00", got.Time)
+		assert.Equal(t, "Задача", got.Title)
+
+		assert.Error(t, store.UpdateTaskDate("", "20240127", ""))
+		assert.Error(t, store.UpdateTaskDate(id, "", ""))
+		assert.Error(t, store.UpdateTaskDate("999", "20240127", ""))
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		store := newStore(t)
+		id := add(t, store, db.Task{Date: "20240126", Title: "Удалить"})
+		keep := add(t, store, db.Task{Date: "20240126", Title: "Оставить"})
+
+		assert.NoError(t, store.DeleteTask(id, now))
+		_, err := store.GetTask(id)
+		assert.True(t, errors.Is(err, sql.ErrNoRows))
+		assert.Error(t, store.DeleteTask(id, now))
+		assert.Error(t, store.DeleteTask("abc", now))
+
+		tasks, err := store.Tasks(10, "", now)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Оставить"}, titles(tasks))
+		_, err = store.GetTask(keep)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Trash", func(t *testing.T) {
+		store := newStore(t)
+		first := add(t, store, db.Task{Date: "20240126", Title: "Купить хлеб", Repeat: "d 1"})
+		second := add(t, store, db.Task{Date: "20240127", Title: "Купить молоко"})
+		add(t, store, db.Task{Date: "20240128", Title: "Хлеб и молоко"})
+
+		trash, err := store.Trash()
+		assert.NoError(t, err)
+		assert.NotNil(t, trash)
+		assert.Empty(t, trash)
+
+		assert.NoError(t, store.DeleteTask(first, now))
+		assert.NoError(t, store.DeleteTask(second, now.Add(time.Hour)))
+
+		// задачи в корзине не видны в списках, поиске, фильтрах и не меняются
+		tasks, err := store.Tasks(10, "", now)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Хлеб и молоко"}, titles(tasks))
+		tasks, err = store.Tasks(10, "купить", now)
+		assert.NoError(t, err)
+		assert.Empty(t, tasks)
+		tasks, err = store.Tasks(10, "26.01.2024", now)
+		assert.NoError(t, err)
+		assert.Empty(t, tasks)
+		filter, err := db.ParseFilter("repeat:yes")
+		assert.NoError(t, err)
+		page, err := store.TasksPage(10, db.TaskQuery{Filter: filter, Now: now}, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, page.Tasks)
+		assert.Equal(t, 0, page.Total)
+		assert.Error(t, store.UpdateTask(&db.Task{ID: first, Date: "20240126", Title: "Другое"}))
+		assert.Error(t, store.UpdateTaskDate(first, "20240127", ""))
+
+		trash, err = store.Trash()
+		assert.NoError(t, err)
+		if assert.Len(t, trash, 2) {
+			assert.Equal(t, []string{"Купить молоко", "Купить хлеб"}, titles(trash))
+			assert.Equal(t, "2024-01-26T13:00:00Z", trash[0].DeletedAt)
+			assert.Equal(t, "2024-01-26T12:00:00Z", trash[1].DeletedAt)
+			assert.Equal(t, "d 1", trash[1].Repeat)
+		}
+
+		assert.NoError(t, store.RestoreTask(first))
+		assert.Error(t, store.RestoreTask(first))
+		assert.Error(t, store.PurgeTask(first))
+		task, err := store.GetTask(first)
+		assert.NoError(t, err)
+		assert.Empty(t, task.DeletedAt)
+		tasks, err = store.Tasks(10, "купить", now)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Купить хлеб"}, titles(tasks))
+
+		// задача, удалённая ровно before, остаётся в корзине
+		count, err := store.PurgeTrash(now.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), count)
+		count, err = store.PurgeTrash(now.Add(time.Hour + time.Second))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+		trash, err = store.Trash()
+		assert.NoError(t, err)
+		assert.Empty(t, trash)
+		assert.Error(t, store.RestoreTask(second))
+
+		// время удаления хранится в UTC
+		assert.NoError(t, store.DeleteTask(first, time.Date(2024, 1, 26, 15, 0, 0, 0, time.FixedZone("MSK", 3*3600))))
+		trash, err = store.Trash()
+		assert.NoError(t, err)
+		if assert.Len(t, trash, 1) {
+			assert.Equal(t, "2024-01-26T12:00:00Z", trash[0].DeletedAt)
+		}
+		assert.NoError(t, store.PurgeTask(first))
+		assert.Error(t, store.PurgeTask(first))
+		assert.Error(t, store.PurgeTask("abc"))
+	})
+
+	t.Run("Exceptions", func(t *testing.T) {
+		store := newStore(t)
+		id := add(t, store, db.Task{Date: "20240126", Title: "Пропуски", Repeat: "d 1"})
+		other := add(t, store, db.Task{Date: "20240126", Title: "Другая", Repeat: "d 1"})
+
+		except, err := store.Exceptions(id)
+		assert.NoError(t, err)
+		assert.NotNil(t, except)
+		assert.Empty(t, except)
+
+		assert.NoError(t, store.AddException(id, "20240130"))
+		assert.NoError(t, store.AddException(id, "20240128"))
+		assert.NoError(t, store.AddException(id, "20240130"))
+		assert.NoError(t, store.AddException(other, "20240127"))
+		assert.Error(t, store.AddException("", "20240127"))
+		assert.Error(t, store.AddException(id, ""))
+
+		except, err = store.Exceptions(id)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"20240128", "20240130"}, except)
+
+		assert.NoError(t, store.DeleteException(id, "20240128"))
+		assert.Error(t, store.DeleteException(id, "20240128"))
+		except, err = store.Exceptions(id)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"20240130"}, except)
+
+		// исключения остаются, пока задача в корзине, и удаляются вместе с ней
+		assert.NoError(t, store.DeleteTask(id, now))
+		except, err = store.Exceptions(id)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"20240130"}, except)
+		assert.NoError(t, store.PurgeTask(id))
+		except, err = store.Exceptions(id)
+		assert.NoError(t, err)
+		assert.Empty(t, except)
+		except, err = store.Exceptions(other)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"20240127"}, except)
+	})
+
+	t.Run("Completions", func(t *testing.T) {
+		store := newStore(t)
+		completions, err := store.Completions("", "", "")
+		assert.NoError(t, err)
+		assert.NotNil(t, completions)
+		assert.Empty(t, completions)
+
+		for _, c := range []db.Completion{
+			{TaskID: "1", Title: "Зарядка", Date: "20240125", DoneDate: "20240125", CompletedAt: "2024-01-25T07:00:00Z", NextDate: "20240126"},
+			{TaskID: "1", Title: "Зарядка", Date: "20240126", Time: "07:00", DoneDate: "20240126",
+				CompletedAt: "2024-01-26T07:00:00Z", Note: "10 минут", NextDate: "20240127"},
+			{TaskID: "2", Title: "Отчёт", Date: "20240126", DoneDate: "20240127", CompletedAt: "2024-01-26T21:30:00Z"},
+			{TaskID: "01", Title: "Зарядка", Date: "20240127", DoneDate: "20240127", CompletedAt: "2024-01-26T21:30:00Z", NextDate: "20240128"},
+		} {
+			_, err = store.AddCompletion(&c)
+			assert.NoError(t, err)
+		}
+		_, err = store.AddCompletion(&db.Completion{Date: "20240126"})
+		assert.Error(t, err)
+
+		ids := func(completions []db.Completion) []string {
+			result := make([]string, 0, len(completions))
+			for _, c := range completions {
+				result = append(result, c.ID)
+			}
+			return result
+		}
+
+		// последние первыми, при равном времени - по убыванию id
+		completions, err = store.Completions("", "", "")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"4", "3", "2", "1"}, ids(completions))
+
+		completions, err = store.Completions("1", "", "")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"4", "2", "1"}, ids(completions))
+		if assert.Len(t, completions, 3) {
+			assert.Equal(t, db.Completion{
+				ID: "2", TaskID: "1", Title: "Зарядка", Date: "20240126", Time: "07:00", DoneDate: "20240126",
+				CompletedAt: "2024-01-26T07:00:00Z", Note: "10 минут", NextDate: "20240127",
+			}, completions[1])
+			assert.Equal(t, "1", completions[0].TaskID)
+		}
+
+		// диапазон - по дню выполнения
+		completions, err = store.Completions("", "20240126", "20240126")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"2"}, ids(completions))
+		completions, err = store.Completions("1", "20240126", "")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"4", "2"}, ids(completions))
+		completions, err = store.Completions("", "", "20240125")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"1"}, ids(completions))
+		completions, err = store.Completions("abc", "", "")
+		assert.NoError(t, err)
+		assert.Empty(t, completions)
+	})
+
+	t.Run("CompleteTask", func(t *testing.T) {
+		store := newStore(t)
+		id := add(t, store, db.Task{Date: "20240126", Time: "07:00", Title: "Зарядка", Repeat: "d 1", RepeatCount: 3})
+		other := add(t, store, db.Task{Date: "20240126", Title: "Отчёт", Comment: "за январь"})
+
+		_, err := store.CompleteTask(&db.Completion{TaskID: id, Date: "20240126", NextDate: "20240127"}, "07:30", now)
+		assert.NoError(t, err)
+		task, err := store.GetTask(id)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "20240127", task.Date)
+			assert.Equal(t, "07:30", task.Time)
+			assert.Equal(t, 2, task.RepeatCount)
+		}
+
+		_, err = store.CompleteTask(&db.Completion{TaskID: other, Date: "20240126"}, "", now)
+		assert.NoError(t, err)
+		_, err = store.GetTask(other)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		trash, err := store.Trash()
+		assert.NoError(t, err)
+		if assert.Len(t, trash, 1) {
+			assert.Equal(t, "2024-01-26T12:00:00Z", trash[0].DeletedAt)
+			assert.Equal(t, "за январь", trash[0].Comment)
+		}
+
+		// если задачу не удалось перенести, выполнение не записывается
+		for _, taskID := range []string{other, "123", ""} {
+			_, err = store.CompleteTask(&db.Completion{TaskID: taskID, Date: "20240126", NextDate: "20240127"}, "", now)
+			assert.Error(t, err, taskID)
+		}
+		completions, err := store.Completions("", "", "")
+		assert.NoError(t, err)
+		assert.Len(t, completions, 2)
+	})
+
+	t.Run("Holidays", func(t *testing.T) {
+		store := newStore(t)
+		holidays, err := store.Holidays()
+		assert.NoError(t, err)
+		assert.NotNil(t, holidays)
+		assert.Empty(t, holidays)
+
+		assert.NoError(t, store.AddHolidays([]db.Holiday{
+			{Date: "20240308", Title: "8 Марта"},
+			{Date: "20240101", Title: "Новый год"},
+		}))
+		assert.NoError(t, store.AddHolidays([]db.Holiday{{Date: "20240308", Title: "Женский день"}}))
+
+		// ошибка в одной дате не добавляет остальные
+		assert.Error(t, store.AddHolidays([]db.Holiday{{Date: "20240501", Title: "Первомай"}, {Title: "Без даты"}}))
+
+		holidays, err = store.Holidays()
+		assert.NoError(t, err)
+		assert.Equal(t, []db.Holiday{
+			{Date: "20240101", Title: "Новый год"},
+			{Date: "20240308", Title: "Женский день"},
+		}, holidays)
+
+		assert.NoError(t, store.DeleteHoliday("20240101"))
+		assert.Error(t, store.DeleteHoliday("20240101"))
+		holidays, err = store.Holidays()
+		assert.NoError(t, err)
+		assert.Equal(t, []db.Holiday{{Date: "20240308", Title: "Женский день"}}, holidays)
+	})
+}
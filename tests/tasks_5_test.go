@@ -24,7 +24,7 @@ func addTask(t *testing.T, task task) string {
 	return id
 }
 
-func getTasks(t *testing.T, search string) []map[string]string {
+func getTasks(t *testing.T, search string) []map[string]any {
 	url := "api/tasks"
 	if Search {
 		url += "?search=" + search
@@ -32,10 +32,12 @@ func getTasks(t *testing.T, search string) []map[string]string {
 	body, err := requestJSON(url, nil, http.MethodGet)
 	assert.NoError(t, err)
 
-	var m map[string][]map[string]string
-	err = json.Unmarshal(body, &m)
+	var resp struct {
+		Tasks []map[string]any `json:"tasks"`
+	}
+	err = json.Unmarshal(body, &resp)
 	assert.NoError(t, err)
-	return m["tasks"]
+	return resp.Tasks
 }
 
 func TestTasks(t *testing.T) {
@@ -0,0 +1,14 @@
+-- база первой версии планировщика: без schema_version, времени задач и правил окончания
+CREATE TABLE scheduler (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    date CHAR(8) NOT NULL DEFAULT '',
+    title TEXT NOT NULL DEFAULT '',
+    comment TEXT NOT NULL DEFAULT '',
+    repeat CHAR(128) NOT NULL DEFAULT ''
+);
+CREATE INDEX idx_scheduler_date ON scheduler(date);
+
+INSERT INTO scheduler (date, title, comment, repeat) VALUES
+    ('20240126', 'Фитнес', 'Тренировка', 'd 2'),
+    ('20240201', 'Оплатить интернет', '', 'm 1'),
+    ('20240315', 'Прочитать книгу', 'Глава 3', '');
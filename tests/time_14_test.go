@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDateTime(t *testing.T) {
+	tbl := []struct {
+		now, date, time, repeat, want string
+	}{
+		{"20240126", "20240126", "09:30", "h 4", "20240126 13:30"},
+		{"20240126 12:00", "20240126", "09:30", "h 4", "20240126 13:30"},
+		{"20240126 14:00", "20240126", "09:30", "h 4", "20240126 17:30"},
+		{"20240126 22:00", "20240126", "21:00", "h 4", "20240127 01:00"},
+		{"20240126", "20240120", "18:00", "d 1", "20240127 18:00"},
+		{"20240126", "20240126", "25:00", "h 4", ""},
+	}
+	for _, v := range tbl {
+		body, err := getBody(fmt.Sprintf("api/nextdate?now=%s&date=%s&time=%s&repeat=%s",
+			strings.ReplaceAll(v.now, " ", "%20"), v.date, v.time, strings.ReplaceAll(v.repeat, " ", "%20")))
+		assert.NoError(t, err)
+		next := strings.TrimSpace(string(body))
+		if v.want == "" {
+			_, err = time.Parse("20060102 15:04", next)
+			assert.Error(t, err, "%v", v)
+			continue
+		}
+		assert.Equal(t, v.want, next, "%v", v)
+	}
+}
+
+func TestTaskTime(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	tomorrow := now.AddDate(0, 0, 1).Format(`20060102`)
+
+	tbl := []map[string]any{
+		{"title": "Неверное время", "time": "25:00"},
+		{"title": "Неверная длительность", "time": "10:00", "duration": -5},
+		{"title": "Слишком долго", "time": "10:00", "duration": 24*60 + 1},
+		{"title": "Без времени", "repeat": "h 2"},
+	}
+	for _, v := range tbl {
+		m, err := postJSON("api/task", v, http.MethodPost)
+		assert.NoError(t, err)
+		e, ok := m["error"]
+		assert.False(t, !ok || len(fmt.Sprint(e)) == 0, "Ожидается ошибка для задачи %v", v)
+	}
+
+	m, err := postJSON("api/task", map[string]any{
+		"date":     tomorrow,
+		"time":     "09:30",
+		"duration": 45,
+		"title":    "Стендап",
+		"repeat":   "d 1",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(m["id"])
+
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var task map[string]any
+	err = json.Unmarshal(body, &task)
+	assert.NoError(t, err)
+	assert.Equal(t, "09:30", task["time"])
+	assert.Equal(t, float64(45), task["duration"])
+
+	ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	var stored Task
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 2).Format(`20060102`), stored.Date)
+	assert.Equal(t, "09:30", stored.Time)
+
+	m, err = postJSON("api/task", map[string]any{
+		"date":   tomorrow,
+		"time":   "10:00",
+		"title":  "Проветрить",
+		"repeat": "h 2",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id = fmt.Sprint(m["id"])
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, tomorrow, stored.Date)
+	assert.Equal(t, "12:00", stored.Time)
+
+	m, err = postJSON("api/task", map[string]any{
+		"date":   now.AddDate(0, 0, -1).Format(`20060102`),
+		"time":   "10:00",
+		"title":  "Выпить воды",
+		"repeat": "h 24",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id = fmt.Sprint(m["id"])
+
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, "10:00", stored.Time)
+	assert.GreaterOrEqual(t, stored.Date, now.Format(`20060102`))
+}
+
+func TestTasksOrderByTime(t *testing.T) {
+	date := time.Now().AddDate(0, 0, 40)
+
+	for _, clock := range []string{"18:00", "", "09:00"} {
+		m, err := postJSON("api/task", map[string]any{
+			"date":  date.Format(`20060102`),
+			"time":  clock,
+			"title": "Дела на " + clock,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, m["id"])
+	}
+
+	if !Search {
+		return
+	}
+	tasks := getTasks(t, date.Format(`02.01.2006`))
+	times := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		clock, _ := task["time"].(string)
+		times = append(times, clock)
+	}
+	assert.Equal(t, []string{"", "09:00", "18:00"}, times)
+}
@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Часовые поясы с разницей 25 часов: сегодня в east всегда позже, чем сегодня в west
+const (
+	tzEast = "Pacific/Kiritimati"
+	tzWest = "Pacific/Pago_Pago"
+)
+
+func todayIn(t *testing.T, name string) string {
+	loc, err := time.LoadLocation(name)
+	assert.NoError(t, err)
+	return time.Now().In(loc).Format(`20060102`)
+}
+
+func TestTimezoneAddTask(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	eastToday := todayIn(t, tzEast)
+	westToday := todayIn(t, tzWest)
+	assert.Less(t, westToday, eastToday)
+
+	// для пользователя на западе сегодняшняя дата востока ещё в будущем
+	m, err := postJSON("api/task?tz="+tzWest, map[string]any{
+		"date":  eastToday,
+		"title": "Созвон с Кирибати",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(m["id"])
+
+	var stored Task
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, eastToday, stored.Date)
+
+	// для пользователя на востоке сегодняшняя дата запада уже прошла
+	m, err = postJSON("api/task?tz="+tzEast, map[string]any{
+		"date":  westToday,
+		"title": "Созвон с Самоа",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id = fmt.Sprint(m["id"])
+
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, eastToday, stored.Date)
+
+	m, err = postJSON("api/task?tz=Mars/Olympus", map[string]any{
+		"title": "Неверный часовой пояс",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, m["error"])
+}
+
+func TestTimezoneNextDate(t *testing.T) {
+	body, err := getBody("api/nextdate?now=20240126&date=20240126&repeat=d%201&tz=" + tzEast)
+	assert.NoError(t, err)
+	assert.Equal(t, "20240127", strings.TrimSpace(string(body)))
+
+	// без now используется текущий день пользователя
+	body, err = getBody(fmt.Sprintf("api/nextdate?date=%s&repeat=d%%201&tz=%s", todayIn(t, tzWest), tzEast))
+	assert.NoError(t, err)
+	loc, err := time.LoadLocation(tzEast)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Now().In(loc).AddDate(0, 0, 1).Format(`20060102`), strings.TrimSpace(string(body)))
+
+	body, err = getBody("api/nextdate?now=20240126&date=20240126&repeat=d%201&tz=Mars/Olympus")
+	assert.NoError(t, err)
+	assert.NotEqual(t, "20240127", strings.TrimSpace(string(body)))
+}
+
+func TestTimezoneSearch(t *testing.T) {
+	if !Search {
+		return
+	}
+	eastToday := todayIn(t, tzEast)
+
+	m, err := postJSON("api/task", map[string]any{
+		"date":  eastToday,
+		"title": "Задача на сегодня по Кирибати",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(m["id"])
+
+	for _, search := range []string{"сегодня", "today"} {
+		body, err := requestJSON("api/tasks?search="+search+"&tz="+tzEast, nil, http.MethodGet)
+		assert.NoError(t, err)
+		var resp struct {
+			Tasks []map[string]any `json:"tasks"`
+		}
+		err = json.Unmarshal(body, &resp)
+		assert.NoError(t, err)
+
+		found := false
+		for _, task := range resp.Tasks {
+			assert.Equal(t, eastToday, task["date"])
+			if task["id"] == id {
+				found = true
+			}
+		}
+		assert.True(t, found, "задача не найдена по %s", search)
+	}
+}
@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ezfroze/go_final_project/pkg/db"
+	"github.com/ezfroze/go_final_project/pkg/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func getTrash(t *testing.T, url string) []map[string]any {
+	code, body := serverDo(t, http.MethodGet, url+"/api/trash", "", nil)
+	assert.Equal(t, http.StatusOK, code, body)
+
+	var resp struct {
+		Tasks []map[string]any `json:"tasks"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(body), &resp), body)
+	return resp.Tasks
+}
+
+func TestTrash(t *testing.T) {
+	now := time.Date(2024, 1, 26, 12, 0, 0, 0, time.UTC)
+	clock := now
+	ts := newTestServer(t, server.Config{Timezone: "UTC", TrashDays: 30}, db.NewMemoryStore(),
+		func() time.Time { return clock })
+
+	done := serverAddTask(t, ts.URL, map[string]any{"date": "20240126", "title": "Выполнить"})
+	deleted := serverAddTask(t, ts.URL, map[string]any{"date": "20240127", "title": "Удалить", "repeat": "d 2"})
+
+	// выполненная задача без повторения и удалённая задача попадают в корзину
+	code, body := serverDo(t, http.MethodPost, ts.URL+"/api/task/done?id="+done, "", nil)
+	assert.Equal(t, http.StatusOK, code, body)
+	clock = now.Add(time.Minute)
+	code, body = serverDo(t, http.MethodDelete, ts.URL+"/api/task?id="+deleted, "", nil)
+	assert.Equal(t, http.StatusOK, code, body)
+
+	_, body = serverDo(t, http.MethodGet, ts.URL+"/api/task?id="+deleted, "", nil)
+	assert.Contains(t, body, `"error"`)
+	code, body = serverDo(t, http.MethodGet, ts.URL+"/api/tasks", "", nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"tasks": [], "total": 0}`, body)
+
+	trash := getTrash(t, ts.URL)
+	if assert.Len(t, trash, 2) {
+		assert.Equal(t, deleted, trash[0]["id"])
+		assert.Equal(t, "2024-01-26T12:01:00Z", trash[0]["deleted_at"])
+		assert.Equal(t, "каждые 2 дня", trash[0]["repeat_description"])
+		assert.Equal(t, done, trash[1]["id"])
+		assert.Equal(t, "2024-01-26T12:00:00Z", trash[1]["deleted_at"])
+	}
+
+	code, body = serverDo(t, http.MethodPost, ts.URL+"/api/trash/restore?id="+deleted, "", nil)
+	assert.Equal(t, http.StatusOK, code, body)
+	code, body = serverDo(t, http.MethodGet, ts.URL+"/api/task?id="+deleted, "", nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotContains(t, body, "deleted_at")
+
+	for _, v := range []struct {
+		method, path string
+		code         int
+	}{
+		{http.MethodPost, "/api/trash/restore?id=" + deleted, http.StatusBadRequest},
+		{http.MethodPost, "/api/trash/restore", http.StatusBadRequest},
+		{http.MethodGet, "/api/trash/restore?id=" + done, http.StatusMethodNotAllowed},
+		{http.MethodDelete, "/api/trash?id=" + deleted, http.StatusBadRequest},
+		{http.MethodDelete, "/api/trash", http.StatusBadRequest},
+		{http.MethodPost, "/api/trash", http.StatusMethodNotAllowed},
+	} {
+		code, body = serverDo(t, v.method, ts.URL+v.path, "", nil)
+		assert.Equal(t, v.code, code, "%s %s", v.method, v.path)
+		assert.Contains(t, body, `"error"`, "%s %s", v.method, v.path)
+	}
+
+	code, body = serverDo(t, http.MethodDelete, ts.URL+"/api/trash?id="+done, "", nil)
+	assert.Equal(t, http.StatusOK, code, body)
+	assert.Empty(t, getTrash(t, ts.URL))
+
+	// задачи старше срока хранения удаляются из корзины
+	code, body = serverDo(t, http.MethodDelete, ts.URL+"/api/task?id="+deleted, "", nil)
+	assert.Equal(t, http.StatusOK, code, body)
+	clock = clock.AddDate(0, 0, 30)
+	assert.Len(t, getTrash(t, ts.URL), 1)
+	clock = clock.Add(time.Second)
+	assert.Empty(t, getTrash(t, ts.URL))
+}
+
+func TestTrashPurgeOnStart(t *testing.T) {
+	now := time.Date(2024, 1, 26, 12, 0, 0, 0, time.UTC)
+	store := db.NewMemoryStore()
+	for _, deletedAt := range []time.Time{now.AddDate(0, 0, -8), now.AddDate(0, 0, -6)} {
+		id, err := store.AddTask(&db.Task{Date: "20240101", Title: "Старая"})
+		assert.NoError(t, err)
+		assert.NoError(t, store.DeleteTask(strconv.FormatInt(id, 10), deletedAt))
+	}
+
+	// без срока хранения корзина не очищается
+	newTestServer(t, server.Config{Timezone: "UTC"}, store, fixedClock(now))
+	trash, err := store.Trash()
+	assert.NoError(t, err)
+	assert.Len(t, trash, 2)
+
+	newTestServer(t, server.Config{Timezone: "UTC", TrashDays: 7}, store, fixedClock(now))
+	trash, err = store.Trash()
+	assert.NoError(t, err)
+	assert.Len(t, trash, 1)
+}
+
+func TestTrashConfig(t *testing.T) {
+	t.Setenv("TODO_TRASH_DAYS", "")
+	cfg, err := server.ConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, server.DefaultTrashDays, cfg.TrashDays)
+
+	t.Setenv("TODO_TRASH_DAYS", "0")
+	cfg, err = server.ConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, cfg.TrashDays)
+
+	for _, days := range []string{"-1", "неделя"} {
+		t.Setenv("TODO_TRASH_DAYS", days)
+		_, err = server.ConfigFromEnv()
+		assert.Error(t, err, days)
+	}
+}
@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ezfroze/go_final_project/pkg/api"
+	"github.com/ezfroze/go_final_project/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDateYearly(t *testing.T) {
+	now := time.Date(2024, 1, 26, 0, 0, 0, 0, time.UTC)
+
+	tbl := []nextDate{
+		{"20240101", "y 2", "20260101"},
+		{"20230615", "y 2", "20250615"},
+		{"20220615", "y 2", "20240615"},
+		{"19000301", "y 100", "21000301"},
+		{"20240229", "y", "20250301"},
+		{"20200229", "y", "20240229"},
+		{"20240229", "y clamp", "20250228"},
+		{"20240229", "y 4", "20280229"},
+		{"20240101", "y 25.12,01.01", "20241225"},
+		{"20231201", "y 25.12,01.01 2", "20250101"},
+		{"20240101", "y 15.03 5", "20240315"},
+		{"20240101", "y 29.02", "20240229"},
+		{"20240301", "y 29.02", "20250301"},
+		{"20240301", "y 29.02 clamp", "20250228"},
+		{"20240101", "y 01.02 fwd", "20240201"},
+		{"20240101", "FREQ=YEARLY;INTERVAL=2", "20260101"},
+		{"20240101", "FREQ=YEARLY;INTERVAL=3;BYMONTH=3,9;BYMONTHDAY=1,15", "20240301"},
+		{"20240101", "y 0", ""},
+		{"20240101", "y 101", ""},
+		{"20240101", "y 30.02", ""},
+		{"20240101", "y 25.13", ""},
+		{"20240101", "y 25.12 2 3", ""},
+		{"20240101", "y 1.1.1", ""},
+		{"20240101", "y 2 25.12", ""},
+		{"20240101", "FREQ=YEARLY;INTERVAL=2;BYMONTH=2;BYMONTHDAY=29", ""},
+		{"20240101", "FREQ=YEARLY;INTERVAL=101", ""},
+	}
+	for _, v := range tbl {
+		next, err := api.NextDate(now, v.date, v.repeat)
+		if v.want == "" {
+			assert.Error(t, err, "%q %q", v.date, v.repeat)
+			continue
+		}
+		assert.NoError(t, err, "%q %q", v.date, v.repeat)
+		assert.Equal(t, v.want, next, "%q %q", v.date, v.repeat)
+	}
+}
+
+func TestYearlyConvertAndDescribe(t *testing.T) {
+	rule, err := api.RuleFromRepeat("y 25.12,01.01 2")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, rule.Interval)
+	assert.Equal(t, []db.RepeatYearDay{{Day: 25, Month: 12}, {Day: 1, Month: 1}}, rule.Dates)
+	repeat, err := api.RepeatFromRule(rule)
+	assert.NoError(t, err)
+	assert.Equal(t, "y 25.12,01.01 2", repeat)
+
+	_, err = api.RepeatFromRule(&db.RepeatRule{Type: "m", Dates: []db.RepeatYearDay{{Day: 1, Month: 1}}})
+	assert.Error(t, err)
+
+	tbl := []struct {
+		repeat, ru, en string
+	}{
+		{"y 2", "каждые 2 года", "every 2 years"},
+		{"y 25.12,01.01", "каждый год 25 декабря и 1 января", "every year on December 25 and January 1"},
+		{"y 29.02 5", "каждые 5 лет 29 февраля, в невисокосные годы 29 февраля переносится на 1 марта",
+			"every 5 years on February 29, February 29 moves to March 1 in common years"},
+		{"y clamp", "каждый год, в невисокосные годы 29 февраля переносится на 28 февраля",
+			"every year, February 29 moves to February 28 in common years"},
+	}
+	for _, v := range tbl {
+		ru, err := api.DescribeRepeat(v.repeat, api.LangRU)
+		assert.NoError(t, err, v.repeat)
+		assert.Equal(t, v.ru, ru, v.repeat)
+		en, err := api.DescribeRepeat(v.repeat, api.LangEN)
+		assert.NoError(t, err, v.repeat)
+		assert.Equal(t, v.en, en, v.repeat)
+	}
+
+	task, err := api.ParseQuickTask("техосмотр каждые 2 года", time.Date(2024, 1, 24, 10, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, "техосмотр", task.Title)
+	assert.Equal(t, "y 2", task.Repeat)
+}